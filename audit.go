@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// auditFormats lists the supported --format values for --audit
+var auditFormats = []string{"text", "json", "csv"}
+
+// auditRow describes how one release is represented in the changelog and in git
+type auditRow struct {
+	Version       string `json:"version"`
+	Date          string `json:"date"`
+	TagExists     bool   `json:"tag_exists"`
+	SectionExists bool   `json:"section_exists"`
+	Signed        bool   `json:"signed"`
+}
+
+// tagCandidates returns the tag names a changelog version may be tagged as,
+// e.g. "1.0.0" may be tagged "1.0.0" or "v1.0.0"
+func tagCandidates(version string) []string {
+	if strings.HasPrefix(version, "v") {
+		return []string{version, strings.TrimPrefix(version, "v")}
+	}
+	return []string{version, "v" + version}
+}
+
+// auditWorkers bounds the number of concurrent tagInfo lookups
+var auditWorkers = 8
+
+// componentTags returns every tag, or only those that start with the
+// component prefix of opts if it has one
+func componentTags(opts extractOptions) ([]string, error) {
+	tags, err := listAllTags()
+	if err != nil || opts.prefix == "" {
+		return tags, err
+	}
+	var matching []string
+	for _, name := range tags {
+		if strings.HasPrefix(name, opts.prefix) {
+			matching = append(matching, name)
+		}
+	}
+	return matching, nil
+}
+
+// auditReleases cross-references the changelog sections with the git tags.
+// Rows for changelog sections come first in file order, followed by tags
+// that have no changelog section in sorted order. A section is tagged with
+// the component prefix of opts in front of its version, and other
+// components' tags are left out.
+func auditReleases(sections []changelogSection, opts extractOptions) ([]auditRow, error) {
+	tags, err := componentTags(opts)
+	if err != nil {
+		return nil, err
+	}
+	tagSet := make(map[string]bool, len(tags))
+	for _, name := range tags {
+		tagSet[name] = true
+	}
+
+	// rowTags[i] is the tag backing rows[i], or "" if it has none
+	rows := make([]auditRow, 0, len(sections))
+	var rowTags []string
+	matched := make(map[string]bool)
+	for _, section := range sections {
+		row := auditRow{Version: section.Version, Date: section.Date, SectionExists: true}
+		tag := ""
+		for _, candidate := range tagCandidates(section.Version) {
+			if name := opts.prefix + candidate; tagSet[name] {
+				row.TagExists = true
+				tag = name
+				matched[name] = true
+				break
+			}
+		}
+		rows = append(rows, row)
+		rowTags = append(rowTags, tag)
+	}
+
+	var unmatched []string
+	for _, name := range tags {
+		if !matched[name] {
+			unmatched = append(unmatched, name)
+		}
+	}
+	sort.Strings(unmatched)
+	for _, name := range unmatched {
+		rows = append(rows, auditRow{Version: name, TagExists: true})
+		rowTags = append(rowTags, name)
+	}
+
+	infos, err := tagInfos(rowTags)
+	if err != nil {
+		return nil, err
+	}
+	for i, info := range infos {
+		rows[i].Signed = info.Signed
+	}
+	return rows, nil
+}
+
+// tagInfos runs tagInfo for every non-empty name using at most auditWorkers
+// concurrent lookups. The result is index-aligned with names; empty names
+// yield a zero tagDetails.
+func tagInfos(names []string) ([]tagDetails, error) {
+	infos := make([]tagDetails, len(names))
+	errs := make([]error, len(names))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(auditWorkers, len(names)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				infos[i], errs[i] = tagInfo(names[i])
+			}
+		}()
+	}
+	for i, name := range names {
+		if name != "" {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return infos, nil
+}
+
+// writeAudit writes the audit rows to w in the given format
+func writeAudit(w io.Writer, rows []auditRow, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"version", "date", "tag_exists", "section_exists", "signed"}); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			record := []string{
+				row.Version,
+				row.Date,
+				strconv.FormatBool(row.TagExists),
+				strconv.FormatBool(row.SectionExists),
+				strconv.FormatBool(row.Signed),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case "text":
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tDATE\tTAG\tSECTION\tSIGNED")
+		for _, row := range rows {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", row.Version, row.Date, yesNo(row.TagExists), yesNo(row.SectionExists), yesNo(row.Signed))
+		}
+		return writer.Flush()
+	default:
+		return fmt.Errorf("unknown format '%s' (available: %s)", format, strings.Join(auditFormats, ", "))
+	}
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+// runAudit writes the changelog-to-tag report for changelogFile to output
+func runAudit(changelogFile string, opts extractOptions, format, output string) error {
+	if !contains(auditFormats, format) {
+		return fmt.Errorf("unknown format '%s' (available: %s)", format, strings.Join(auditFormats, ", "))
+	}
+
+	sections, err := parseChangelogSections(changelogFile, opts)
+	if err != nil {
+		return err
+	}
+	rows, err := auditReleases(sections, opts)
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(output)
+	if err != nil {
+		return err
+	}
+	if err := writeAudit(w, rows, format); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAuditRows = []auditRow{
+	{Version: "v1.0.1", Date: "2025-08-27", TagExists: true, SectionExists: true, Signed: true},
+	{Version: "v1.0.0", Date: "2025-08-26", TagExists: false, SectionExists: true, Signed: false},
+	{Version: "legacy,import", TagExists: true, SectionExists: false, Signed: false},
+}
+
+func TestWriteAuditCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeAudit(&buf, testAuditRows, "csv"); err != nil {
+		t.Fatalf("writeAudit() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("writeAudit() produced invalid CSV: %v", err)
+	}
+
+	want := [][]string{
+		{"version", "date", "tag_exists", "section_exists", "signed"},
+		{"v1.0.1", "2025-08-27", "true", "true", "true"},
+		{"v1.0.0", "2025-08-26", "false", "true", "false"},
+		{"legacy,import", "", "true", "false", "false"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("writeAudit() CSV records = %q, want %q", records, want)
+	}
+}
+
+func TestWriteAuditJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeAudit(&buf, testAuditRows, "json"); err != nil {
+		t.Fatalf("writeAudit() error = %v", err)
+	}
+
+	var got []auditRow
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("writeAudit() produced invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, testAuditRows) {
+		t.Errorf("writeAudit() JSON rows = %+v, want %+v", got, testAuditRows)
+	}
+}
+
+func TestWriteAuditUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeAudit(&buf, testAuditRows, "xml"); err == nil {
+		t.Error("writeAudit() with unknown format expected error, got nil")
+	}
+}
+
+func TestTagCandidates(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{"v1.0.0", []string{"v1.0.0", "1.0.0"}},
+		{"1.0.0", []string{"1.0.0", "v1.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := tagCandidates(tt.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tagCandidates(%q) = %q, want %q", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeAuditGit serves "git tag -l" and tagInfo lookups for the given tags,
+// sleeping for latency on every call to mimic spawning git
+func fakeAuditGit(tb testing.TB, tags []string, signed map[string]bool, latency time.Duration) {
+	fakeGit(tb, func(stdin string, args []string) (string, error) {
+		time.Sleep(latency)
+		switch args[0] {
+		case "tag":
+			return strings.Join(tags, "\n") + "\n", nil
+		case "for-each-ref":
+			name := strings.TrimPrefix(args[len(args)-1], "refs/tags/")
+			signature := ""
+			if signed[name] {
+				signature = "-----BEGIN PGP SIGNATURE-----\n"
+			}
+			return "tag\x00c0ffee\x00beef\x00Release " + name + "\n" + signature + "\x00" + signature + "\n", nil
+		}
+		// The handler runs on tagInfos workers, where Fatalf must not be called
+		tb.Errorf("unexpected git call: %q", args)
+		return "", fmt.Errorf("unexpected git call: %q", args)
+	})
+}
+
+func TestAuditReleases(t *testing.T) {
+	fakeAuditGit(t, []string{"1.0.0", "experiment", "v1.0.1"}, map[string]bool{"v1.0.1": true}, 0)
+
+	sections := []changelogSection{
+		{Version: "v1.1.0", Date: "2025-08-28"},
+		{Version: "v1.0.1", Date: "2025-08-27"},
+		{Version: "v1.0.0", Date: "2025-08-26"},
+	}
+	got, err := auditReleases(sections, extractOptions{})
+	if err != nil {
+		t.Fatalf("auditReleases() error = %v", err)
+	}
+
+	want := []auditRow{
+		{Version: "v1.1.0", Date: "2025-08-28", TagExists: false, SectionExists: true},
+		{Version: "v1.0.1", Date: "2025-08-27", TagExists: true, SectionExists: true, Signed: true},
+		{Version: "v1.0.0", Date: "2025-08-26", TagExists: true, SectionExists: true},
+		{Version: "experiment", TagExists: true, SectionExists: false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("auditReleases() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestAuditReleasesPrefix(t *testing.T) {
+	fakeAuditGit(t, []string{"frontend-v1.0.0", "v1.1.0", "backend-v1.1.0", "frontend-v0.9.0"}, nil, 0)
+
+	sections := []changelogSection{{Version: "1.1.0"}, {Version: "1.0.0"}}
+	got, err := auditReleases(sections, extractOptions{prefix: "frontend-"})
+	if err != nil {
+		t.Fatalf("auditReleases() error = %v", err)
+	}
+
+	// Other components' tags neither match sections nor get rows
+	want := []auditRow{
+		{Version: "1.1.0", SectionExists: true},
+		{Version: "1.0.0", TagExists: true, SectionExists: true},
+		{Version: "frontend-v0.9.0", TagExists: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("auditReleases() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func BenchmarkAuditReleases(b *testing.B) {
+	const releases = 500
+	tags := make([]string, releases)
+	sections := make([]changelogSection, releases)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("v1.%d.0", i)
+		sections[i] = changelogSection{Version: tags[i]}
+	}
+	// Roughly the cost of spawning a short-lived git process
+	fakeAuditGit(b, tags, nil, 200*time.Microsecond)
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			original := auditWorkers
+			auditWorkers = workers
+			defer func() {
+				auditWorkers = original
+			}()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := auditReleases(sections, extractOptions{}); err != nil {
+					b.Fatalf("auditReleases() error = %v", err)
+				}
+			}
+		})
+	}
+}
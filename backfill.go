@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parseCommitMap reads a --commit-map file of "<version> <commit>" lines.
+// Blank lines and lines starting with "#" are ignored.
+func parseCommitMap(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	commits := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected '<version> <commit>', got %q", lineNum, line)
+		}
+		if _, ok := commits[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate version '%s'", lineNum, fields[0])
+		}
+		commits[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// backfillPlan selects the audit rows for changelog versions that have no
+// tag and returns a batch entry for each one with a commit in commits, in
+// changelog order. Versions without a commit are returned as skipped. A
+// version may be mapped with or without its "v" prefix; the tag is named
+// as in the changelog, after the component prefix.
+func backfillPlan(rows []auditRow, commits map[string]string, prefix string) (entries []batchEntry, skipped []string) {
+	for _, row := range rows {
+		if !row.SectionExists || row.TagExists {
+			continue
+		}
+		commit := ""
+		for _, name := range tagCandidates(row.Version) {
+			if commit = commits[name]; commit != "" {
+				break
+			}
+		}
+		if commit == "" {
+			skipped = append(skipped, row.Version)
+			continue
+		}
+		entries = append(entries, batchEntry{Tag: prefix + row.Version, Commit: commit})
+	}
+	return entries, skipped
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommitMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"versions and comments", "# releases\nv1.0.0 abc123\n\n1.1.0 def456\n", map[string]string{"v1.0.0": "abc123", "1.1.0": "def456"}, false},
+		{"missing commit", "v1.0.0\n", nil, true},
+		{"extra field", "v1.0.0 abc123 extra\n", nil, true},
+		{"duplicate version", "v1.0.0 abc123\nv1.0.0 def456\n", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "commits.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write commit map: %v", err)
+			}
+			got, err := parseCommitMap(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommitMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCommitMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackfillPlan(t *testing.T) {
+	rows := []auditRow{
+		{Version: "v1.2.0", SectionExists: true},
+		{Version: "v1.1.0", SectionExists: true, TagExists: true},
+		{Version: "1.0.0", SectionExists: true},
+		{Version: "v0.9.0", SectionExists: true},
+		{Version: "v0.1.0", TagExists: true},
+	}
+	commits := map[string]string{
+		"v1.2.0": "abc123",
+		"v1.1.0": "def456",
+		"v1.0.0": "0a1b2c",
+	}
+
+	entries, skipped := backfillPlan(rows, commits, "")
+	wantEntries := []batchEntry{{Tag: "v1.2.0", Commit: "abc123"}, {Tag: "1.0.0", Commit: "0a1b2c"}}
+	if !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("backfillPlan() entries = %+v, want %+v", entries, wantEntries)
+	}
+	if wantSkipped := []string{"v0.9.0"}; !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("backfillPlan() skipped = %v, want %v", skipped, wantSkipped)
+	}
+	// A component's tags carry its prefix; the commit map uses versions
+	entries, _ = backfillPlan(rows, commits, "frontend-")
+	wantEntries = []batchEntry{{Tag: "frontend-v1.2.0", Commit: "abc123"}, {Tag: "frontend-1.0.0", Commit: "0a1b2c"}}
+	if !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("backfillPlan() with a prefix entries = %+v, want %+v", entries, wantEntries)
+	}
+}
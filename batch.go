@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// batchEntry is one line of a --batch file
+type batchEntry struct {
+	Tag string
+	// Commit is the revision to tag; empty means HEAD
+	Commit string
+}
+
+// batchOptions controls how runBatch creates the tags
+type batchOptions struct {
+	force           bool
+	forceMove       bool
+	noOverwrite     bool
+	skipIfUnchanged bool
+	notesDir        string
+	reachableFrom   string
+	// tagOpts is passed to createTag with the commit of each entry
+	tagOpts tagOptions
+	// state records the handled tags of a resumable run, if set
+	state *batchState
+	// delay is the pause between two created tags
+	delay time.Duration
+}
+
+// sleep pauses a batch between tags; tests replace it
+var sleep = time.Sleep
+
+// batchState is the --resume state file of a batch run: the tags that were
+// already handled, in order
+type batchState struct {
+	path string
+	Done []string `json:"done"`
+	done map[string]bool
+}
+
+// loadBatchState reads the state file at path. A missing file, or restart,
+// yields an empty state; restart also removes the old file.
+func loadBatchState(path string, restart bool) (*batchState, error) {
+	state := &batchState{path: path, done: make(map[string]bool)}
+	if restart {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("state file %s is corrupt (%v); use --restart to start over", path, err)
+	}
+	for _, tag := range state.Done {
+		state.done[tag] = true
+	}
+	return state, nil
+}
+
+// isDone reports whether tagName was handled by an earlier run
+func (s *batchState) isDone(tagName string) bool {
+	return s.done[tagName]
+}
+
+// markDone records tagName as handled. The file is replaced atomically so an
+// interruption leaves either the old or the new state.
+func (s *batchState) markDone(tagName string) error {
+	if s.done[tagName] {
+		return nil
+	}
+	s.Done = append(s.Done, tagName)
+	s.done[tagName] = true
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// parseBatchFile reads a batch file of "<tag> [<commit>]" lines. Blank lines
+// and lines starting with "#" are ignored.
+func parseBatchFile(path string) ([]batchEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []batchEntry
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected '<tag> [<commit>]', got %q", lineNum, line)
+		}
+		tag := shortTagName(fields[0])
+		if err := checkTagName(tag); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		entry := batchEntry{Tag: tag}
+		if len(fields) == 2 {
+			entry.Commit = fields[1]
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no tags listed in %s", path)
+	}
+	return entries, nil
+}
+
+// notesFileName returns a file name for the notes of tagName, replacing
+// path separators and other characters that are unsafe in file names
+func notesFileName(tagName string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, tagName) + ".md"
+}
+
+// writeNotes writes message to the notes file of tagName in dir, creating
+// dir if needed, and returns the path written
+func writeNotes(dir, tagName, message string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, notesFileName(tagName))
+	if err := os.WriteFile(path, []byte(message+"\n"), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// runBatch creates a tag for every entry. Existing tags are skipped unless
+// opts.force is set, or fail the whole batch before any tag is created if
+// opts.noOverwrite is set. It stops at the first tag that cannot be created.
+// With opts.state, tags handled by an earlier run are skipped and each
+// handled tag is recorded, so an interrupted batch can be run again.
+// opts.delay is waited between two created tags; skipped tags don't wait.
+func runBatch(entries []batchEntry, builder messageBuilder, opts batchOptions) error {
+	var pending []batchEntry
+	for _, entry := range entries {
+		if opts.state == nil || !opts.state.isDone(entry.Tag) {
+			pending = append(pending, entry)
+		}
+	}
+	if resumed := len(entries) - len(pending); resumed > 0 {
+		printSuccess(fmt.Sprintf("Resuming from %s: %d of %d tag(s) already done", opts.state.path, resumed, len(entries)))
+	}
+
+	if opts.noOverwrite {
+		for _, entry := range pending {
+			if err := checkNoOverwrite(entry.Tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.tagOpts.sign && len(pending) > 0 {
+		if err := preflightSigning(opts.tagOpts); err != nil {
+			return err
+		}
+	}
+
+	var created, skipped int
+	for _, entry := range pending {
+		commit := entry.Commit
+		if commit == "" {
+			commit = "HEAD"
+		}
+		if _, err := shortCommit(commit); err != nil {
+			return fmt.Errorf("cannot resolve commit '%s' for tag '%s'", commit, entry.Tag)
+		}
+		if opts.reachableFrom != "" {
+			if err := checkReachable(commit, opts.reachableFrom); err != nil {
+				return fmt.Errorf("tag '%s': %w", entry.Tag, err)
+			}
+		}
+
+		exists := tagExists(entry.Tag)
+		if exists && !opts.force {
+			printWarning(fmt.Sprintf("Tag '%s' already exists, skipping", entry.Tag))
+			skipped++
+			if err := opts.recordDone(entry.Tag); err != nil {
+				return err
+			}
+			continue
+		}
+
+		moved := false
+		if exists {
+			var err error
+			if moved, err = checkTagMove(entry.Tag, commit, opts.forceMove); err != nil {
+				return err
+			}
+		}
+
+		message, _, err := builder.build(entry.Tag, commit)
+		if err != nil {
+			return err
+		}
+		if exists && opts.skipIfUnchanged && !moved {
+			unchanged, err := tagMessageUnchanged(entry.Tag, message)
+			if err != nil {
+				return fmt.Errorf("failed to read existing tag '%s': %v", entry.Tag, err)
+			}
+			if unchanged {
+				printSuccess(fmt.Sprintf("Tag '%s' already has this message, skipping", entry.Tag))
+				skipped++
+				if err := opts.recordDone(entry.Tag); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		// Pause before touching an existing tag, so that an interrupted
+		// delay never leaves it deleted
+		if created > 0 && opts.delay > 0 {
+			sleep(opts.delay)
+		}
+		if exists {
+			if err := deleteTag(entry.Tag); err != nil {
+				return fmt.Errorf("failed to delete existing tag '%s': %v", entry.Tag, err)
+			}
+		}
+		tagOpts := opts.tagOpts
+		tagOpts.commit = commit
+		if err := createTag(entry.Tag, message, tagOpts); err != nil {
+			return fmt.Errorf("failed to create tag '%s': %v", entry.Tag, err)
+		}
+		printSuccess(fmt.Sprintf("✓ Tag '%s' created at %s", entry.Tag, commit))
+		created++
+
+		if opts.notesDir != "" {
+			path, err := writeNotes(opts.notesDir, entry.Tag, message)
+			if err != nil {
+				return fmt.Errorf("failed to write notes for '%s': %v", entry.Tag, err)
+			}
+			printSuccess(fmt.Sprintf("Wrote %s", path))
+		}
+		if err := opts.recordDone(entry.Tag); err != nil {
+			return err
+		}
+	}
+
+	printSuccess(fmt.Sprintf("Created %d tag(s), skipped %d", created, skipped))
+	return nil
+}
+
+// recordDone marks tagName as handled in the state file, if resuming
+func (opts batchOptions) recordDone(tagName string) error {
+	if opts.state == nil {
+		return nil
+	}
+	if err := opts.state.markDone(tagName); err != nil {
+		return fmt.Errorf("failed to update state file %s: %v", opts.state.path, err)
+	}
+	return nil
+}
+
+// gpgExec runs a gpg tool with the given arguments, feeding stdin to the
+// process, and returns its standard output. On failure the error includes
+// the tool's standard error. Tests replace it with a fake.
+var gpgExec = func(stdin, program string, args ...string) ([]byte, error) {
+	output, stderr, err := runCommand(exec.Command(program, args...), stdin)
+	if msg := strings.TrimSpace(stderr); err != nil && msg != "" {
+		return output, fmt.Errorf("%w: %s", err, msg)
+	}
+	return output, err
+}
+
+// preflightSigning prepares a signed batch: it signs a test payload with
+// the key of opts, so gpg asks for the passphrase once before the first tag
+// and gpg-agent answers for the tags that follow. It warns when gpg-agent
+// is not running or does not cache passphrases, and fails if the test
+// signature fails, before any tag is created. SSH and X.509 signatures are
+// left to their own tools.
+func preflightSigning(opts tagOptions) error {
+	if opts.signFormat != "" && opts.signFormat != "openpgp" {
+		return nil
+	}
+	program := "gpg"
+	if output, err := runGit("config", "gpg.program"); err == nil && strings.TrimSpace(string(output)) != "" {
+		program = strings.TrimSpace(string(output))
+	}
+	key := opts.keyID
+	if key == "" {
+		if output, err := runGit("config", "user.signingkey"); err == nil {
+			key = strings.TrimSpace(string(output))
+		}
+	}
+
+	if _, err := gpgExec("", "gpg-connect-agent", "/bye"); err != nil {
+		printWarning("gpg-agent is not running; gpg may ask for the passphrase for every tag")
+	} else if ttl, ok := gpgAgentCacheTTL(); ok && ttl == 0 {
+		printWarning("gpg-agent does not cache passphrases (default-cache-ttl is 0); gpg may ask for the passphrase for every tag")
+	}
+
+	args := []string{"--detach-sign", "--armor"}
+	if key != "" {
+		args = append(args, "--local-user", key)
+	}
+	printSuccess("Checking the signing key before the batch...")
+	if _, err := gpgExec("gtauto signing check\n", program, args...); err != nil {
+		return fmt.Errorf("could not sign with %s, no tag was created: %v", program, err)
+	}
+	return nil
+}
+
+// gpgAgentCacheTTL returns the default-cache-ttl of gpg-agent in seconds,
+// as reported by gpgconf. ok is false if gpgconf is unavailable or the
+// option is missing.
+func gpgAgentCacheTTL() (ttl int, ok bool) {
+	output, err := gpgExec("", "gpgconf", "--list-options", "gpg-agent")
+	if err != nil {
+		return 0, false
+	}
+	// Fields are name:flags:level:description:type:alt-type:argname:default:argdef:value
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, ":")
+		if fields[0] != "default-cache-ttl" || len(fields) < 10 {
+			continue
+		}
+		value := fields[9]
+		if value == "" {
+			value = fields[7]
+		}
+		ttl, err := strconv.Atoi(value)
+		return ttl, err == nil
+	}
+	return 0, false
+}
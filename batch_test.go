@@ -0,0 +1,375 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBatchFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []batchEntry
+		wantErr string
+	}{
+		{
+			name:    "tags and commits",
+			content: "# releases\nv1.0.0 abc123\n\nv1.1.0\n",
+			want:    []batchEntry{{Tag: "v1.0.0", Commit: "abc123"}, {Tag: "v1.1.0"}},
+		},
+		{
+			name:    "fully-qualified refs",
+			content: "refs/tags/v1.0.0 abc123\n",
+			want:    []batchEntry{{Tag: "v1.0.0", Commit: "abc123"}},
+		},
+		{"too many fields", "v1.0.0 abc123 extra\n", nil, "line 1"},
+		{"empty tag name", "v main\n", nil, "line 1"},
+		{"no tags", "# nothing yet\n", nil, "no tags listed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "batch.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write batch file: %v", err)
+			}
+
+			got, err := parseBatchFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseBatchFile() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseBatchFile() unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseBatchFile() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parseBatchFile()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNotesFileName(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"v1.0.0", "v1.0.0.md"},
+		{"release/v1.0.0", "release_v1.0.0.md"},
+		{`pkg\a:b`, "pkg_a_b.md"},
+	}
+
+	for _, tt := range tests {
+		if got := notesFileName(tt.tag); got != tt.want {
+			t.Errorf("notesFileName(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestRunBatchNotesDir(t *testing.T) {
+	dir := initTestRepo(t)
+	first := gitCmd(t, "rev-parse", "HEAD")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "second")
+
+	changelog := filepath.Join(dir, "CHANGELOG.md")
+	content := "# Changelog\n\n## [v1.1.0]\n- Second\n\n## [v1.0.0]\n- First\n"
+	if err := os.WriteFile(changelog, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+
+	notesDir := filepath.Join(dir, "notes", "nested")
+	entries := []batchEntry{{Tag: "release/v1.0.0", Commit: first}, {Tag: "v1.1.0"}}
+	builder := messageBuilder{changelogFile: changelog, extract: extractOptions{headingLevel: defaultHeadingLevel}}
+	if err := runBatch(entries, builder, batchOptions{notesDir: notesDir}); err != nil {
+		t.Fatalf("runBatch() unexpected error: %v", err)
+	}
+
+	if got := gitCmd(t, "rev-list", "-n", "1", "release/v1.0.0"); got != first {
+		t.Errorf("release/v1.0.0 points at %s, want %s", got, first)
+	}
+	for name, want := range map[string]string{
+		"release_v1.0.0.md": "Release release/v1.0.0\n",
+		"v1.1.0.md":         "## [v1.1.0]\n- Second\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(notesDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read notes: %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Existing tags are skipped without --force
+	if err := os.RemoveAll(notesDir); err != nil {
+		t.Fatalf("Failed to remove notes: %v", err)
+	}
+	if err := runBatch(entries, builder, batchOptions{notesDir: notesDir}); err != nil {
+		t.Fatalf("runBatch() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(notesDir); !os.IsNotExist(err) {
+		t.Errorf("notes written for skipped tags: %v", err)
+	}
+}
+
+func TestRunBatchNoOverwrite(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "v1.1.0")
+
+	entries := []batchEntry{{Tag: "v1.0.0"}, {Tag: "v1.1.0"}}
+	builder := messageBuilder{changelogFile: "CHANGELOG.md", extract: extractOptions{headingLevel: defaultHeadingLevel}}
+	err := runBatch(entries, builder, batchOptions{force: true, noOverwrite: true})
+	if err == nil || !strings.Contains(err.Error(), "v1.1.0") {
+		t.Fatalf("runBatch() error = %v, want existing v1.1.0 rejected", err)
+	}
+	if got := gitCmd(t, "tag", "-l", "v1.0.0"); got != "" {
+		t.Errorf("runBatch() created %s despite the rejected batch", got)
+	}
+}
+
+func TestLoadBatchState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "batch.state")
+
+	state, err := loadBatchState(path, false)
+	if err != nil {
+		t.Fatalf("loadBatchState() missing file error = %v", err)
+	}
+	if err := state.markDone("v1.0.0"); err != nil {
+		t.Fatalf("markDone() error = %v", err)
+	}
+	if err := state.markDone("v1.0.0"); err != nil {
+		t.Fatalf("markDone() error = %v", err)
+	}
+
+	loaded, err := loadBatchState(path, false)
+	if err != nil {
+		t.Fatalf("loadBatchState() error = %v", err)
+	}
+	if !loaded.isDone("v1.0.0") || loaded.isDone("v1.1.0") || len(loaded.Done) != 1 {
+		t.Errorf("loadBatchState() done = %v, want [v1.0.0]", loaded.Done)
+	}
+
+	restarted, err := loadBatchState(path, true)
+	if err != nil {
+		t.Fatalf("loadBatchState() restart error = %v", err)
+	}
+	if restarted.isDone("v1.0.0") {
+		t.Error("loadBatchState() with restart kept the old state")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("restart left the state file behind: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"done": ["v1.0.0"`), 0o644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+	if _, err := loadBatchState(path, false); err == nil || !strings.Contains(err.Error(), "--restart") {
+		t.Errorf("loadBatchState() corrupt file error = %v, want a hint to --restart", err)
+	}
+	if _, err := loadBatchState(path, true); err != nil {
+		t.Errorf("loadBatchState() restart of corrupt file error = %v", err)
+	}
+}
+
+func TestRunBatchResume(t *testing.T) {
+	dir := initTestRepo(t)
+	statePath := filepath.Join(dir, "batch.state")
+
+	// An earlier run that created v1.0.0 before it was interrupted
+	state, err := loadBatchState(statePath, false)
+	if err != nil {
+		t.Fatalf("loadBatchState() error = %v", err)
+	}
+	gitCmd(t, "tag", "-m", "earlier", "v1.0.0")
+	if err := state.markDone("v1.0.0"); err != nil {
+		t.Fatalf("markDone() error = %v", err)
+	}
+
+	entries := []batchEntry{{Tag: "v1.0.0"}, {Tag: "v1.1.0"}}
+	builder := messageBuilder{changelogFile: "CHANGELOG.md", extract: extractOptions{headingLevel: defaultHeadingLevel}}
+	opts := batchOptions{force: true, noOverwrite: true, state: state}
+	if err := runBatch(entries, builder, opts); err != nil {
+		t.Fatalf("runBatch() unexpected error: %v", err)
+	}
+
+	if got := gitCmd(t, "tag", "-l", "--format=%(contents:subject)", "v1.0.0"); got != "earlier" {
+		t.Errorf("v1.0.0 message = %q, want the resumed tag left alone", got)
+	}
+	if got := gitCmd(t, "tag", "-l", "v1.1.0"); got != "v1.1.0" {
+		t.Errorf("runBatch() did not create v1.1.0")
+	}
+	resumed, err := loadBatchState(statePath, false)
+	if err != nil {
+		t.Fatalf("loadBatchState() error = %v", err)
+	}
+	if !resumed.isDone("v1.1.0") {
+		t.Errorf("state file done = %v, want v1.1.0 recorded", resumed.Done)
+	}
+}
+
+func TestRunBatchRefusesMove(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "-a", "v1.0.0", "-m", "Release v1.0.0")
+	first := gitCmd(t, "rev-parse", "HEAD")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "second")
+
+	entries := []batchEntry{{Tag: "v1.0.0"}}
+	builder := messageBuilder{changelogFile: "CHANGELOG.md", extract: extractOptions{headingLevel: defaultHeadingLevel}}
+	if err := runBatch(entries, builder, batchOptions{force: true}); err == nil {
+		t.Fatal("runBatch() moved v1.0.0 without forceMove")
+	}
+	if got := gitCmd(t, "rev-list", "-n", "1", "v1.0.0"); got != first {
+		t.Errorf("v1.0.0 points at %s, want %s", got, first)
+	}
+
+	if err := runBatch(entries, builder, batchOptions{force: true, forceMove: true}); err != nil {
+		t.Fatalf("runBatch() with forceMove error = %v", err)
+	}
+	if got := gitCmd(t, "rev-list", "-n", "1", "v1.0.0"); got == first {
+		t.Error("runBatch() with forceMove left v1.0.0 at the old commit")
+	}
+}
+
+func TestPreflightSigning(t *testing.T) {
+	const ttlOutput = "default-cache-ttl:24:0:expire cached PINs after N seconds:3:3:N:600::%s\n"
+	tests := []struct {
+		name        string
+		opts        tagOptions
+		signingKey  string
+		agentErr    error
+		ttlValue    string
+		signErr     error
+		wantKey     string
+		wantSigned  bool
+		wantWarning string
+		wantErr     bool
+	}{
+		{name: "cached", opts: tagOptions{sign: true, keyID: "ABCD"}, wantKey: "ABCD", wantSigned: true},
+		{name: "key from git config", opts: tagOptions{sign: true}, signingKey: "CFG1", wantKey: "CFG1", wantSigned: true},
+		{name: "no agent", opts: tagOptions{sign: true}, agentErr: errors.New("no agent"), wantSigned: true, wantWarning: "gpg-agent is not running"},
+		{name: "no caching", opts: tagOptions{sign: true}, ttlValue: "0", wantSigned: true, wantWarning: "does not cache passphrases"},
+		{name: "signing fails", opts: tagOptions{sign: true}, signErr: errors.New("no secret key"), wantSigned: true, wantErr: true},
+		{name: "ssh", opts: tagOptions{sign: true, signFormat: "ssh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTestRepo(t)
+			if tt.signingKey != "" {
+				gitCmd(t, "config", "user.signingkey", tt.signingKey)
+			}
+			readStderr := captureStderr(t)
+			var signArgs []string
+			original := gpgExec
+			gpgExec = func(stdin, program string, args ...string) ([]byte, error) {
+				switch program {
+				case "gpg-connect-agent":
+					return nil, tt.agentErr
+				case "gpgconf":
+					return []byte(fmt.Sprintf(ttlOutput, tt.ttlValue)), nil
+				}
+				signArgs = append([]string{program}, args...)
+				return nil, tt.signErr
+			}
+			t.Cleanup(func() { gpgExec = original })
+
+			err := preflightSigning(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("preflightSigning() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if signed := signArgs != nil; signed != tt.wantSigned {
+				t.Fatalf("test signature made = %v, want %v", signed, tt.wantSigned)
+			}
+			if tt.wantKey != "" && !strings.Contains(strings.Join(signArgs, " "), "--local-user "+tt.wantKey) {
+				t.Errorf("test signature args = %q, want --local-user %s", signArgs, tt.wantKey)
+			}
+			stderr := readStderr()
+			if tt.wantWarning == "" && strings.Contains(stderr, "Warning") {
+				t.Errorf("unexpected warning: %q", stderr)
+			}
+			if !strings.Contains(stderr, tt.wantWarning) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestGpgAgentCacheTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   int
+		wantOK bool
+	}{
+		{"default", "default-cache-ttl:24:0:expire cached PINs after N seconds:3:3:N:600::\n", nil, 600, true},
+		{"configured", "max-cache-ttl:24:2:x:3:3:N:7200::\ndefault-cache-ttl:24:0:x:3:3:N:600::0\n", nil, 0, true},
+		{"missing", "max-cache-ttl:24:2:x:3:3:N:7200::\n", nil, 0, false},
+		{"no gpgconf", "", errors.New("not found"), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := gpgExec
+			gpgExec = func(string, string, ...string) ([]byte, error) { return []byte(tt.output), tt.err }
+			t.Cleanup(func() { gpgExec = original })
+
+			got, ok := gpgAgentCacheTTL()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("gpgAgentCacheTTL() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRunBatchDelay(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "-a", "v1.0.0", "-m", "Release v1.0.0")
+	var slept []time.Duration
+	original := sleep
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	t.Cleanup(func() { sleep = original })
+
+	// v1.0.0 exists and is skipped, so only the pause between the two new tags remains
+	entries := []batchEntry{{Tag: "v1.0.0"}, {Tag: "v1.1.0"}, {Tag: "v1.2.0"}}
+	builder := messageBuilder{changelogFile: "CHANGELOG.md", extract: extractOptions{headingLevel: defaultHeadingLevel}}
+	if err := runBatch(entries, builder, batchOptions{delay: 2 * time.Second}); err != nil {
+		t.Fatalf("runBatch() error = %v", err)
+	}
+	if want := []time.Duration{2 * time.Second}; !reflect.DeepEqual(slept, want) {
+		t.Errorf("slept %v, want %v", slept, want)
+	}
+}
+
+func TestRunBatchDelayKeepsTagUntilRecreated(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "-a", "v1.1.0", "-m", "Old notes")
+	original := sleep
+	// The forced tag must still exist while the delay runs
+	sleep = func(time.Duration) {
+		if !tagExists("v1.1.0") {
+			t.Error("v1.1.0 was deleted before the delay")
+		}
+	}
+	t.Cleanup(func() { sleep = original })
+
+	entries := []batchEntry{{Tag: "v1.0.0"}, {Tag: "v1.1.0"}}
+	builder := messageBuilder{changelogFile: "CHANGELOG.md", extract: extractOptions{headingLevel: defaultHeadingLevel}}
+	if err := runBatch(entries, builder, batchOptions{delay: time.Second, force: true}); err != nil {
+		t.Fatalf("runBatch() error = %v", err)
+	}
+}
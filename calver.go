@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calverLayout formats the date of a --calver tag, e.g. "2025.08.27"
+const calverLayout = "2006.01.02"
+
+// nextCalverTag returns the calendar version tag for the date of now, with
+// prefix: "2025.08.27" for the first tag of the day, then "2025.08.27.1",
+// "2025.08.27.2" and so on when existing already has tags for that day.
+func nextCalverTag(existing []string, prefix string, now time.Time) string {
+	base := prefix + now.Format(calverLayout)
+	taken, last := false, 0
+	for _, tag := range existing {
+		if tag == base {
+			taken = true
+			continue
+		}
+		suffix, ok := strings.CutPrefix(tag, base+".")
+		if !ok {
+			continue
+		}
+		// Only plain counters count, so "2025.08.27.rc1" is not one
+		if n, err := strconv.Atoi(suffix); err == nil && n > 0 && strconv.Itoa(n) == suffix {
+			taken = true
+			last = max(last, n)
+		}
+	}
+	if !taken {
+		return base
+	}
+	return fmt.Sprintf("%s.%d", base, last+1)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextCalverTag(t *testing.T) {
+	now := time.Date(2025, 8, 27, 9, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		existing []string
+		prefix   string
+		want     string
+	}{
+		{"first tag of the day", []string{"2025.08.26", "2025.08.26.1", "v1.0.0"}, "", "2025.08.27"},
+		{"second tag of the day", []string{"2025.08.27"}, "", "2025.08.27.1"},
+		{"after the highest counter", []string{"2025.08.27", "2025.08.27.2", "2025.08.27.10"}, "", "2025.08.27.11"},
+		{"counter without the plain date", []string{"2025.08.27.1"}, "", "2025.08.27.2"},
+		{"other suffixes are ignored", []string{"2025.08.27.rc1", "2025.08.27.01", "2025.08.270"}, "", "2025.08.27"},
+		{"with a prefix", []string{"2025.08.27", "app-2025.08.27"}, "app-", "app-2025.08.27.1"},
+		{"no tags", nil, "", "2025.08.27"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextCalverTag(tt.existing, tt.prefix, now); got != tt.want {
+				t.Errorf("nextCalverTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
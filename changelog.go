@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// changelogSection is a version header found in a changelog
+type changelogSection struct {
+	// Version as written in the header, e.g. "v1.0.1" or "1.0.1"
+	Version string
+	// Date is the YYYY-MM-DD date following the version, if any
+	Date string
+	// Line is the 1-based line number of the header
+	Line int
+}
+
+var sectionDateRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// parseChangelogSections returns every version section of changelogFile in
+// file order. opts.headerPattern, when set, replaces the headers of the
+// changelog format.
+func parseChangelogSections(changelogFile string, opts extractOptions) ([]changelogSection, error) {
+	if opts.format == formatRST && opts.headerPattern == nil {
+		return parseChangelogSectionsRST(changelogFile)
+	}
+
+	file, err := os.Open(changelogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// parseHeader returns the version of a header line and the rest of the
+	// line, where a date may be
+	headerRegex := regexp.MustCompile(fmt.Sprintf(`^%s\s+\[?\s*([vV]?[0-9]+\.[0-9]+[^\]\s]*)\s*\]?(.*)$`, opts.heading()))
+	parseHeader := func(line string) (string, string, bool) {
+		match := headerRegex.FindStringSubmatch(line)
+		if match == nil {
+			return "", "", false
+		}
+		return match[1], match[2], true
+	}
+	if opts.headerPattern != nil {
+		group := opts.headerPattern.SubexpIndex(headerVersionGroup)
+		parseHeader = func(line string) (string, string, bool) {
+			match := opts.headerPattern.FindStringSubmatchIndex(line)
+			if match == nil || match[2*group] < 0 {
+				return "", "", false
+			}
+			start, end := match[2*group], match[2*group+1]
+			return strings.TrimSpace(line[start:end]), line[:start] + " " + line[end:], true
+		}
+	}
+
+	var sections []changelogSection
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		version, rest, ok := parseHeader(scanner.Text())
+		if !ok || version == "" {
+			continue
+		}
+		sections = append(sections, changelogSection{
+			Version: version,
+			Date:    sectionDateRegex.FindString(rest),
+			Line:    lineNum,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// anyVersionHeaderRegex matches a version header of any markdown level,
+// capturing the "#" prefix
+var anyVersionHeaderRegex = regexp.MustCompile(`^(#{1,6})\s+` + versionStartPattern + `[0-9]+\.[0-9]+`)
+
+// detectHeadingLevel returns the markdown heading level of the first version
+// header in changelogFile, or 0 if it has none
+func detectHeadingLevel(changelogFile string) (int, error) {
+	file, err := os.Open(changelogFile)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if match := anyVersionHeaderRegex.FindStringSubmatch(scanner.Text()); match != nil {
+			return len(match[1]), nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// defaultChangelogCandidates are the file names tried, in order, when the
+// default CHANGELOG.md does not exist
+const defaultChangelogCandidates = "CHANGELOG.md,CHANGELOG,CHANGELOG.txt,CHANGELOG.rst,CHANGES.md,CHANGES.rst,HISTORY.md,NEWS.md,docs/CHANGELOG.md"
+
+// detectChangelog returns the path of the first file in dir that matches one
+// of candidates. Candidates are slash-separated paths relative to dir; the
+// file name is compared case-insensitively, and missing directories are
+// skipped.
+func detectChangelog(dir string, candidates []string) (string, error) {
+	listings := map[string][]os.DirEntry{}
+	for _, candidate := range candidates {
+		sub, name := filepath.Split(filepath.FromSlash(candidate))
+		entries, listed := listings[sub]
+		if !listed {
+			var err error
+			entries, err = os.ReadDir(filepath.Join(dir, sub))
+			if err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+			listings[sub] = entries
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(entry.Name(), name) {
+				return filepath.Join(dir, sub, entry.Name()), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no CHANGELOG file found in %s (tried %s)", dir, strings.Join(candidates, ", "))
+}
+
+// defaultUnreleasedSections are the Keep a Changelog subsections stubbed
+// out by --reset-unreleased
+const defaultUnreleasedSections = "Added,Changed,Deprecated,Removed,Fixed,Security"
+
+// unreleasedHeader returns the Unreleased section header with stub subsections
+func unreleasedHeader(opts extractOptions, subsections []string) string {
+	var b strings.Builder
+	b.WriteString(opts.heading() + " [Unreleased]\n\n")
+	for _, name := range subsections {
+		b.WriteString(opts.heading() + "# " + name + "\n\n")
+	}
+	return b.String()
+}
+
+// unreleasedRegex matches the Unreleased section header, e.g. "## [Unreleased]"
+func unreleasedRegex(opts extractOptions) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(?i)^%s\s+\[?\s*unreleased\s*\]?`, opts.heading()))
+}
+
+// insertUnreleasedSection adds an empty Unreleased section with the given
+// subsections before the first version section of content. It reports
+// false if content already has an Unreleased section.
+func insertUnreleasedSection(content string, opts extractOptions, subsections []string) (string, bool) {
+	unreleasedRegex := unreleasedRegex(opts)
+	versionRegex := regexp.MustCompile(fmt.Sprintf(`^%s\s+%s[0-9]+\.[0-9]+`, opts.heading(), versionStartPattern))
+
+	lines := strings.SplitAfter(content, "\n")
+	insertAt := len(lines)
+	for i, line := range lines {
+		if unreleasedRegex.MatchString(line) {
+			return content, false
+		}
+		if versionRegex.MatchString(line) {
+			insertAt = i
+			break
+		}
+	}
+
+	header := unreleasedHeader(opts, subsections)
+	before := strings.Join(lines[:insertAt], "")
+	if insertAt == len(lines) && before != "" {
+		// No version sections yet; append after the existing content
+		before = strings.TrimRight(before, "\n") + "\n\n"
+		header = strings.TrimRight(header, "\n") + "\n"
+	}
+	return before + header + strings.Join(lines[insertAt:], ""), true
+}
+
+// extractUnreleasedEntry returns the content of the Unreleased section of
+// changelogFile without its header. It fails if there is no Unreleased
+// section or if it has nothing but blank lines and subsection headings.
+func extractUnreleasedEntry(changelogFile string, opts extractOptions) (string, error) {
+	content, err := os.ReadFile(changelogFile)
+	if err != nil {
+		return "", err
+	}
+	unreleasedRegex := unreleasedRegex(opts)
+	nextVersionRegex := regexp.MustCompile(fmt.Sprintf(`^%s\s+%s[0-9]+\.[0-9]+`, opts.heading(), versionStartPattern))
+
+	var body []string
+	inSection, sectionFound, hasContent := false, false, false
+	for _, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		if !inSection {
+			if unreleasedRegex.MatchString(line) {
+				inSection, sectionFound = true, true
+			}
+			continue
+		}
+		if nextVersionRegex.MatchString(line) || linkReferenceRegex.MatchString(line) {
+			break
+		}
+		body = append(body, line)
+		if strings.TrimSpace(line) != "" && !headingRegex.MatchString(line) {
+			hasContent = true
+		}
+	}
+
+	switch {
+	case !sectionFound:
+		return "", fmt.Errorf("no [Unreleased] section in %s", changelogFile)
+	case !hasContent:
+		return "", fmt.Errorf("the [Unreleased] section of %s is empty", changelogFile)
+	}
+	return strings.TrimRight(strings.Join(body, "\n"), "\n"), nil
+}
+
+// parseReleaseDate returns the --date value as a YYYY-MM-DD date: "today"
+// (or empty) is the date of now in local time, and any other value must be a
+// valid YYYY-MM-DD date
+func parseReleaseDate(value string, now time.Time) (string, error) {
+	if value == "" || value == "today" {
+		return now.Format(sectionDateLayout), nil
+	}
+	date, err := time.Parse(sectionDateLayout, value)
+	if err != nil || date.Format(sectionDateLayout) != value {
+		return "", fmt.Errorf("--date must be YYYY-MM-DD or today, got %q", value)
+	}
+	return value, nil
+}
+
+// releaseHeader returns the version header of a release, e.g.
+// "## [v1.3.0] - 2025-09-01"
+func releaseHeader(opts extractOptions, tagName, date string) string {
+	return fmt.Sprintf("%s [%s] - %s", opts.heading(), opts.version(tagName), date)
+}
+
+// promoteUnreleasedSection renames the Unreleased section header of content
+// to the release header of tagName. It reports false if content has no
+// Unreleased section.
+func promoteUnreleasedSection(content string, opts extractOptions, tagName, date string) (string, bool) {
+	unreleasedRegex := unreleasedRegex(opts)
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		if !unreleasedRegex.MatchString(line) {
+			continue
+		}
+		ending := line[len(strings.TrimRight(line, "\r\n")):]
+		lines[i] = releaseHeader(opts, tagName, date) + ending
+		return strings.Join(lines, ""), true
+	}
+	return content, false
+}
+
+// promoteUnreleased rewrites changelogFile with its Unreleased section
+// renamed to the release header of tagName. It reports false, leaving the
+// file alone, if the file already has a section for tagName or has no
+// Unreleased section.
+func promoteUnreleased(changelogFile string, opts extractOptions, tagName, date string) (bool, error) {
+	if _, err := extractChangelogEntry(tagName, changelogFile, opts); err == nil {
+		return false, nil
+	}
+	info, err := os.Stat(changelogFile)
+	if err != nil {
+		return false, err
+	}
+	content, err := os.ReadFile(changelogFile)
+	if err != nil {
+		return false, err
+	}
+
+	updated, promoted := promoteUnreleasedSection(string(content), opts, tagName, date)
+	if !promoted {
+		return false, nil
+	}
+	return true, os.WriteFile(changelogFile, []byte(updated), info.Mode().Perm())
+}
+
+// resetUnreleased rewrites changelogFile with a fresh Unreleased section.
+// It reports false if the file already has one.
+func resetUnreleased(changelogFile string, opts extractOptions, subsections []string) (bool, error) {
+	info, err := os.Stat(changelogFile)
+	if err != nil {
+		return false, err
+	}
+	content, err := os.ReadFile(changelogFile)
+	if err != nil {
+		return false, err
+	}
+
+	updated, inserted := insertUnreleasedSection(string(content), opts, subsections)
+	if !inserted {
+		return false, nil
+	}
+	return true, os.WriteFile(changelogFile, []byte(updated), info.Mode().Perm())
+}
+
+// findMarkers returns the lines of text containing any of the markers as a
+// whole word, matched case-insensitively, prefixed with their line number
+func findMarkers(text string, markers []string) []string {
+	quoted := make([]string, len(markers))
+	for i, marker := range markers {
+		quoted[i] = regexp.QuoteMeta(marker)
+	}
+	markerRegex := regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+
+	var offending []string
+	for i, line := range strings.Split(text, "\n") {
+		if markerRegex.MatchString(line) {
+			offending = append(offending, fmt.Sprintf("line %d: %s", i+1, strings.TrimSpace(line)))
+		}
+	}
+	return offending
+}
+
+// entryChecksum returns the hex SHA-256 of a changelog entry after
+// normalizing CRLF line endings, trailing whitespace and surrounding blank
+// lines, so that checksums survive editor and platform differences
+func entryChecksum(entry string) string {
+	lines := strings.Split(strings.ReplaceAll(entry, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	normalized := strings.Trim(strings.Join(lines, "\n"), "\n")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// stripHeading removes the leading version header of a changelog entry, a
+// markdown header line or a reStructuredText title with its adornment, and
+// the blank lines after it. An entry with nothing but the header is returned
+// unchanged, since git refuses an empty tag message.
+func stripHeading(entry string) string {
+	first, rest, _ := strings.Cut(entry, "\n")
+	switch {
+	case strings.HasPrefix(first, "#"):
+	case isRSTAdornment(first):
+		// An overline, then the title and its underline
+		_, rest, _ = strings.Cut(rest, "\n")
+		_, rest, _ = strings.Cut(rest, "\n")
+	default:
+		second, after, _ := strings.Cut(rest, "\n")
+		if !isRSTAdornment(second) {
+			return entry
+		}
+		rest = after
+	}
+	rest = strings.TrimLeft(rest, "\r\n")
+	if strings.TrimSpace(rest) == "" {
+		return entry
+	}
+	return rest
+}
+
+// maxSuggestionDistance is the largest edit distance at which a changelog
+// version is still suggested for a mistyped tag
+const maxSuggestionDistance = 1
+
+// closestVersion returns the version from versions that tagName most likely
+// meant, or "" if none is close. Versions with the same major.minor win,
+// nearest patch first; otherwise the smallest edit distance up to
+// maxSuggestionDistance does.
+func closestVersion(tagName string, versions []string) string {
+	target := strings.TrimPrefix(tagName, "v")
+	targetSemver, targetOK := parseSemver(target)
+
+	best, bestPatchDistance := "", -1
+	if targetOK {
+		for _, version := range versions {
+			v, ok := parseSemver(version)
+			if !ok || v.Major != targetSemver.Major || v.Minor != targetSemver.Minor {
+				continue
+			}
+			distance := v.Patch - targetSemver.Patch
+			if distance < 0 {
+				distance = -distance
+			}
+			if bestPatchDistance < 0 || distance < bestPatchDistance {
+				best, bestPatchDistance = version, distance
+			}
+		}
+		if best != "" {
+			return best
+		}
+	}
+
+	bestDistance := maxSuggestionDistance + 1
+	for _, version := range versions {
+		if distance := levenshtein(target, strings.TrimPrefix(version, "v")); distance < bestDistance {
+			best, bestDistance = version, distance
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// orderedItemRegex matches numbered list items such as "1. Item"
+var orderedItemRegex = regexp.MustCompile(`^\s*\d+\.\s+`)
+
+// countBullets counts the list items in a changelog entry, nested ones
+// included: "-", "*" and "+" bullets as well as numbered items
+func countBullets(entry string) int {
+	count := 0
+	for _, line := range strings.Split(entry, "\n") {
+		if bulletRegex.MatchString(line) || orderedItemRegex.MatchString(line) {
+			count++
+		}
+	}
+	return count
+}
+
+// unknownSubsections returns the names of the subsections of entry, one
+// heading level below its version header at level, that are not in
+// allowed. Names are compared case-insensitively and each is listed once.
+func unknownSubsections(entry string, allowed []string, level int) []string {
+	prefix := strings.Repeat("#", level+1)
+	var unknown []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(entry, "\n") {
+		match := headingRegex.FindStringSubmatch(line)
+		if match == nil || match[1] != prefix {
+			continue
+		}
+		name := match[2]
+		if seen[strings.ToLower(name)] || containsFold(allowed, name) {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		unknown = append(unknown, name)
+	}
+	return unknown
+}
+
+// containsFold reports whether value is one of values, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChangelogSections(t *testing.T) {
+	content := `# Changelog
+
+## [Unreleased]
+
+## [v1.1.0-rc.1] - 2025-08-28
+
+### Added
+- Release candidate
+
+## v1.0.1 - 2025-08-27
+
+## [1.0.0]
+
+### Added
+- Initial release
+
+## [0.9.0][v0.9.0-link] - 2025-08-01
+
+## [ V0.8.0 ] - 2025-07-01`
+
+	changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(changelogFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+
+	got, err := parseChangelogSections(changelogFile, extractOptions{})
+	if err != nil {
+		t.Fatalf("parseChangelogSections() error = %v", err)
+	}
+
+	want := []changelogSection{
+		{Version: "v1.1.0-rc.1", Date: "2025-08-28", Line: 5},
+		{Version: "v1.0.1", Date: "2025-08-27", Line: 10},
+		{Version: "1.0.0", Date: "", Line: 12},
+		{Version: "0.9.0", Date: "2025-08-01", Line: 17},
+		{Version: "V0.8.0", Date: "2025-07-01", Line: 19},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChangelogSections() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseChangelogSectionsHeaderPattern(t *testing.T) {
+	content := "Changelog\n\nVersion 1.2.0 — 2025-08-27\n- Second\n\n## [1.1.5]\n\nVersion 1.1.0\n- First\n"
+	changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(changelogFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+	pattern, err := compileHeaderPattern(`^Version (?P<version>\S+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the custom headers count, not the built-in markdown ones
+	got, err := parseChangelogSections(changelogFile, extractOptions{headerPattern: pattern})
+	if err != nil {
+		t.Fatalf("parseChangelogSections() error = %v", err)
+	}
+	want := []changelogSection{
+		{Version: "1.2.0", Date: "2025-08-27", Line: 3},
+		{Version: "1.1.0", Date: "", Line: 8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChangelogSections() = %+v, want %+v", got, want)
+	}
+}
+
+func TestInsertUnreleasedSection(t *testing.T) {
+	subsections := []string{"Added", "Fixed"}
+
+	tests := []struct {
+		name         string
+		content      string
+		want         string
+		wantInserted bool
+	}{
+		{
+			name: "insert before latest version",
+			content: `# Changelog
+
+## [v1.0.0] - 2025-08-26
+
+### Added
+- Initial release
+`,
+			want: `# Changelog
+
+## [Unreleased]
+
+### Added
+
+### Fixed
+
+## [v1.0.0] - 2025-08-26
+
+### Added
+- Initial release
+`,
+			wantInserted: true,
+		},
+		{
+			name: "existing unreleased section is kept",
+			content: `# Changelog
+
+## [unreleased]
+
+## [v1.0.0] - 2025-08-26
+`,
+			want: `# Changelog
+
+## [unreleased]
+
+## [v1.0.0] - 2025-08-26
+`,
+			wantInserted: false,
+		},
+		{
+			name:    "append when there are no versions",
+			content: "# Changelog\n",
+			want: `# Changelog
+
+## [Unreleased]
+
+### Added
+
+### Fixed
+`,
+			wantInserted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, inserted := insertUnreleasedSection(tt.content, extractOptions{}, subsections)
+			if inserted != tt.wantInserted {
+				t.Errorf("insertUnreleasedSection() inserted = %v, want %v", inserted, tt.wantInserted)
+			}
+			if got != tt.want {
+				t.Errorf("insertUnreleasedSection() content mismatch\nGot:\n%s\n\nWant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMarkers(t *testing.T) {
+	markers := []string{"TODO", "FIXME", "XXX"}
+
+	tests := []struct {
+		name    string
+		section string
+		want    []string
+	}{
+		{
+			name: "clean section",
+			section: `## [v1.0.0] - 2025-08-26
+
+### Added
+- Todolist view
+- Initial release`,
+			want: nil,
+		},
+		{
+			name: "section with markers",
+			section: `## [v1.0.0] - 2025-08-26
+
+### Added
+- TODO: describe feature
+- Initial release
+- fixme link to docs`,
+			want: []string{
+				"line 4: - TODO: describe feature",
+				"line 6: - fixme link to docs",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findMarkers(tt.section, markers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findMarkers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntryChecksum(t *testing.T) {
+	entry := "## [v1.0.0] - 2025-08-26\n\n### Added\n- Initial release"
+	want := entryChecksum(entry)
+	if len(want) != 64 {
+		t.Fatalf("entryChecksum() = %q, want 64 hex characters", want)
+	}
+
+	tests := []struct {
+		name  string
+		entry string
+		same  bool
+	}{
+		{"identical", entry, true},
+		{"CRLF line endings", strings.ReplaceAll(entry, "\n", "\r\n"), true},
+		{"trailing whitespace and blank lines", "\n" + strings.ReplaceAll(entry, "\n", "  \n") + "\n\n", true},
+		{"changed content", entry + "\n- Another change", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryChecksum(tt.entry); (got == want) != tt.same {
+				t.Errorf("entryChecksum() = %s, want same as %s: %v", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestClosestVersion(t *testing.T) {
+	versions := []string{"v1.1.0", "v1.0.3", "v1.0.1", "0.9.0", "2024.10"}
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"v1.0.4", "v1.0.3"},
+		{"v1.0.2", "v1.0.3"},
+		{"v1.1.5", "v1.1.0"},
+		{"v0.9.1", "0.9.0"},
+		{"v1.10.0", "v1.1.0"},
+		{"2024.1", "2024.10"},
+		{"v3.0.0", ""},
+	}
+
+	for _, tt := range tests {
+		if got := closestVersion(tt.tag, versions); got != tt.want {
+			t.Errorf("closestVersion(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", 1},
+		{"1.0", "1.0.0", 2},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCountBullets(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		want  int
+	}{
+		{"no bullets", "## [v1.0.0]\n\nInitial release.", 0},
+		{"top-level bullets", "## [v1.0.0]\n\n### Added\n- One\n* Two\n+ Three", 3},
+		{"nested bullets", "### Added\n- Parent\n  - Child\n    * Grandchild\n\t- Tab child", 4},
+		{"numbered items", "### Changed\n1. First\n2. Second\n   10. Nested", 3},
+		{"not bullets", "### Notes\n-not a bullet\n**bold**\n1.0.0 release\n---", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countBullets(tt.entry); got != tt.want {
+				t.Errorf("countBullets() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripHeading(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		want  string
+	}{
+		{"header and blank line", "## [v1.0.1] - 2025-08-27\n\n### Added\n- One", "### Added\n- One"},
+		{"header without blank line", "## v1.0.1\n- One\n- Two", "- One\n- Two"},
+		{"blank lines inside kept", "## v1.0.1\n\n\n- One\n\n- Two", "- One\n\n- Two"},
+		{"CRLF line endings", "## v1.0.1\r\n\r\n- One", "- One"},
+		{"header only is kept", "## [v1.0.1] - 2025-08-27\n\n", "## [v1.0.1] - 2025-08-27\n\n"},
+		{"no header", "Release v1.0.1", "Release v1.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripHeading(tt.entry); got != tt.want {
+				t.Errorf("stripHeading() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownSubsections(t *testing.T) {
+	allowed := []string{"Added", "Changed", "Fixed"}
+	tests := []struct {
+		name  string
+		entry string
+		level int
+		want  []string
+	}{
+		{"all allowed", "## [v1.0.0]\n\n### Added\n- One\n\n### Fixed\n- Two", 2, nil},
+		{"case-insensitive", "## [v1.0.0]\n\n### added\n- One", 2, nil},
+		{"offenders in order", "## [v1.0.0]\n\n### Misc\n- One\n\n### Added\n- Two\n\n### Breaking\n- Three", 2, []string{"Misc", "Breaking"}},
+		{"listed once", "## [v1.0.0]\n\n### Misc\n- One\n\n### misc\n- Two", 2, []string{"Misc"}},
+		{"deeper headings ignored", "## [v1.0.0]\n\n### Added\n#### Internals\n- One", 2, nil},
+		{"heading level", "### [v1.0.0]\n\n#### Notes\n- One", 3, []string{"Notes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unknownSubsections(tt.entry, allowed, tt.level); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unknownSubsections() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractUnreleasedEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "content until the next version",
+			content: "# Changelog\n\n## [Unreleased]\n\n### Added\n- New feature\n\n## [v1.0.0] - 2025-08-26\n- Initial release\n",
+			want:    "\n### Added\n- New feature",
+		},
+		{
+			name:    "lower case",
+			content: "# Changelog\n\n## [unreleased]\n- Fix\n\n## [ v1.0.0 ]\n- Initial release\n",
+			want:    "- Fix",
+		},
+		{
+			name:    "upper case with spaces inside the brackets",
+			content: "# Changelog\n\n## [ UNRELEASED ]\n- Fix\n\n## V1.0.0\n- Initial release\n",
+			want:    "- Fix",
+		},
+		{
+			name:    "stops at link references",
+			content: "# Changelog\n\n## Unreleased\n- Fix\n\n[Unreleased]: https://example.com/compare/v1.0.0...HEAD\n",
+			want:    "- Fix",
+		},
+		{
+			name:    "stub subsections only",
+			content: "# Changelog\n\n## [Unreleased]\n\n### Added\n\n### Fixed\n\n## [v1.0.0]\n- Initial release\n",
+			wantErr: "is empty",
+		},
+		{
+			name:    "no unreleased section",
+			content: "# Changelog\n\n## [v1.0.0]\n- Initial release\n",
+			wantErr: "no [Unreleased] section",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write changelog: %v", err)
+			}
+			got, err := extractUnreleasedEntry(path, extractOptions{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("extractUnreleasedEntry() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractUnreleasedEntry() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractUnreleasedEntry() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromoteUnreleased(t *testing.T) {
+	content := "# Changelog\n\n## [Unreleased]\r\n\n- Fix\n\n## [v1.0.0] - 2025-08-26\n- Initial release\n"
+
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+	promoted, err := promoteUnreleased(path, extractOptions{}, "v1.1.0", "2025-09-01")
+	if err != nil || !promoted {
+		t.Fatalf("promoteUnreleased() = %v, %v, want true", promoted, err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Changelog\n\n## [v1.1.0] - 2025-09-01\r\n\n- Fix\n\n## [v1.0.0] - 2025-08-26\n- Initial release\n"
+	if string(got) != want {
+		t.Errorf("CHANGELOG = %q, want %q", got, want)
+	}
+
+	// The version now has a section, so nothing is renamed again
+	if promoted, err := promoteUnreleased(path, extractOptions{}, "v1.1.0", "2025-09-02"); err != nil || promoted {
+		t.Errorf("second promoteUnreleased() = %v, %v, want false", promoted, err)
+	}
+
+	if _, promoted := promoteUnreleasedSection("# Changelog\n\n## [v1.0.0]\n", extractOptions{}, "v1.1.0", "2025-09-01"); promoted {
+		t.Error("promoteUnreleasedSection() promoted a changelog without an Unreleased section")
+	}
+}
+
+func TestDetectChangelog(t *testing.T) {
+	candidates := splitList(defaultChangelogCandidates)
+	tests := []struct {
+		name    string
+		files   []string
+		want    string
+		wantErr bool
+	}{
+		{"changes", []string{"README.md", "CHANGES.md"}, "CHANGES.md", false},
+		{"history in lower case", []string{"history.md"}, "history.md", false},
+		{"first candidate wins", []string{"HISTORY.md", "Changes.md"}, "Changes.md", false},
+		{"without an extension", []string{"NEWS.md", "ChangeLog"}, "ChangeLog", false},
+		{"text file", []string{"CHANGELOG.txt"}, "CHANGELOG.txt", false},
+		{"news", []string{"NEWS.md"}, "NEWS.md", false},
+		{"in docs", []string{"docs/", "docs/CHANGELOG.md"}, "docs/CHANGELOG.md", false},
+		{"root before docs", []string{"docs/", "docs/CHANGELOG.md", "NEWS.md"}, "NEWS.md", false},
+		{"directories are ignored", []string{"changes.md/"}, "", true},
+		{"none found", []string{"README.md"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				var err error
+				if strings.HasSuffix(name, "/") {
+					err = os.Mkdir(filepath.Join(dir, name), 0o755)
+				} else {
+					err = os.WriteFile(filepath.Join(dir, name), []byte("# Changelog\n"), 0o644)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := detectChangelog(dir, candidates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectChangelog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != filepath.Join(dir, tt.want) {
+				t.Errorf("detectChangelog() = %q, want %q", got, filepath.Join(dir, tt.want))
+			}
+		})
+	}
+}
+
+func TestDetectHeadingLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{"level 2", "# Changelog\n\n## [v1.0.0] - 2025-08-26\n- Initial\n", 2},
+		{"level 1", "# v1.0.0\n- Initial\n", 1},
+		{"level 3 below a document title", "# Project\n\n## History\n\n### 1.2.0\n- Fix\n\n### 1.1.0\n", 3},
+		{"unreleased is skipped", "# Changelog\n\n### [Unreleased]\n\n### [1.0.0]\n", 3},
+		{"no version headers", "# Changelog\n\nNothing yet\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write changelog: %v", err)
+			}
+			got, err := detectHeadingLevel(path)
+			if err != nil {
+				t.Fatalf("detectHeadingLevel() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectHeadingLevel() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := detectHeadingLevel(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("detectHeadingLevel() of a missing file returned nil error")
+	}
+}
+
+func TestParseReleaseDate(t *testing.T) {
+	now := time.Date(2025, 9, 1, 23, 30, 0, 0, time.Local)
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"today", "2025-09-01", false},
+		{"", "2025-09-01", false},
+		{"2024-02-29", "2024-02-29", false},
+		{"2025-02-29", "", true},
+		{"2025-9-1", "", true},
+		{"01/09/2025", "", true},
+		{"yesterday", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReleaseDate(tt.value, now)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseReleaseDate(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCandidates lists the clipboard commands to try on goos, in order
+// of preference
+func clipboardCandidates(goos string) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	default:
+		// clip.exe covers WSL, where the Windows clipboard is reachable
+		return [][]string{
+			{"wl-copy"},
+			{"xclip", "-selection", "clipboard"},
+			{"xsel", "--clipboard", "--input"},
+			{"clip.exe"},
+		}
+	}
+}
+
+// copyToClipboard copies text to the system clipboard using the first
+// clipboard command available on this system
+func copyToClipboard(text string) error {
+	for _, args := range clipboardCandidates(runtime.GOOS) {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	var names []string
+	for _, args := range clipboardCandidates(runtime.GOOS) {
+		names = append(names, args[0])
+	}
+	return errors.New("no clipboard tool found (tried " + strings.Join(names, ", ") + ")")
+}
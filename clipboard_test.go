@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClipboardCandidates(t *testing.T) {
+	tests := []struct {
+		goos  string
+		first string
+	}{
+		{"darwin", "pbcopy"},
+		{"windows", "clip.exe"},
+		{"linux", "wl-copy"},
+		{"freebsd", "wl-copy"},
+	}
+
+	for _, tt := range tests {
+		candidates := clipboardCandidates(tt.goos)
+		if len(candidates) == 0 || candidates[0][0] != tt.first {
+			t.Errorf("clipboardCandidates(%q) = %q, want %s first", tt.goos, candidates, tt.first)
+		}
+	}
+}
+
+func TestCopyToClipboardWithoutTool(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	err := copyToClipboard("notes")
+	if err == nil || !strings.Contains(err.Error(), "no clipboard tool found") {
+		t.Errorf("copyToClipboard() without tools error = %v, want no clipboard tool found", err)
+	}
+}
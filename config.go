@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames lists the repository config files, in lookup order
+var configFileNames = []string{".gtauto.yml", ".gtauto.yaml"}
+
+// configValues holds option defaults. Pointer fields distinguish an unset
+// value from an explicit zero value so that layers can be merged.
+type configValues struct {
+	Changelog     *string `yaml:"changelog"`
+	Force         *bool   `yaml:"force"`
+	Remote        *string `yaml:"remote"`
+	Sign          *bool   `yaml:"sign"`
+	ErrorPrefix   *string `yaml:"error_prefix"`
+	WarningPrefix *string `yaml:"warning_prefix"`
+	SuccessPrefix *string `yaml:"success_prefix"`
+}
+
+// Config is the content of a .gtauto.yml file: top-level defaults plus
+// named profiles that override them.
+type Config struct {
+	configValues `yaml:",inline"`
+	Profiles     map[string]configValues `yaml:"profiles"`
+}
+
+// merge returns c with every value set in other taking precedence
+func (c Config) merge(other Config) Config {
+	merged := Config{configValues: c.configValues.merge(other.configValues)}
+	if len(c.Profiles) == 0 && len(other.Profiles) == 0 {
+		return merged
+	}
+
+	merged.Profiles = make(map[string]configValues, len(c.Profiles)+len(other.Profiles))
+	for name, values := range c.Profiles {
+		merged.Profiles[name] = values
+	}
+	for name, values := range other.Profiles {
+		merged.Profiles[name] = merged.Profiles[name].merge(values)
+	}
+	return merged
+}
+
+// merge returns c with every value set in other taking precedence
+func (c configValues) merge(other configValues) configValues {
+	if other.Changelog != nil {
+		c.Changelog = other.Changelog
+	}
+	if other.Force != nil {
+		c.Force = other.Force
+	}
+	if other.Remote != nil {
+		c.Remote = other.Remote
+	}
+	if other.Sign != nil {
+		c.Sign = other.Sign
+	}
+	if other.ErrorPrefix != nil {
+		c.ErrorPrefix = other.ErrorPrefix
+	}
+	if other.WarningPrefix != nil {
+		c.WarningPrefix = other.WarningPrefix
+	}
+	if other.SuccessPrefix != nil {
+		c.SuccessPrefix = other.SuccessPrefix
+	}
+	return c
+}
+
+// flagValues maps the values that are set to their command-line flag names
+func (c configValues) flagValues() map[string]string {
+	values := make(map[string]string)
+	if c.Changelog != nil {
+		values["changelog"] = *c.Changelog
+	}
+	if c.Force != nil {
+		values["force"] = strconv.FormatBool(*c.Force)
+	}
+	if c.Remote != nil {
+		values["remote"] = *c.Remote
+	}
+	if c.Sign != nil {
+		values["sign"] = strconv.FormatBool(*c.Sign)
+	}
+	if c.ErrorPrefix != nil {
+		values["error-prefix"] = *c.ErrorPrefix
+	}
+	if c.WarningPrefix != nil {
+		values["warning-prefix"] = *c.WarningPrefix
+	}
+	if c.SuccessPrefix != nil {
+		values["success-prefix"] = *c.SuccessPrefix
+	}
+	return values
+}
+
+// resolvePaths makes relative file paths absolute with respect to dir.
+// Config files are shared between platforms, so both "/" and "\" are
+// accepted as separators.
+func (c configValues) resolvePaths(dir string) configValues {
+	if c.Changelog != nil {
+		path := portablePath(*c.Changelog)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		c.Changelog = &path
+	}
+	return c
+}
+
+// portablePath converts both "/" and "\" separators in path to the native
+// separator and cleans the result
+func portablePath(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+// profileNames returns the names of all defined profiles in sorted order
+func (c Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve returns the effective values for the given profile. An empty
+// profile name selects the top-level defaults only.
+func (c Config) resolve(profile string) (configValues, error) {
+	if profile == "" {
+		return c.configValues, nil
+	}
+	values, ok := c.Profiles[profile]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return configValues{}, fmt.Errorf("unknown profile '%s' (no profiles defined)", profile)
+		}
+		return configValues{}, fmt.Errorf("unknown profile '%s' (available: %s)", profile, strings.Join(c.profileNames(), ", "))
+	}
+	return c.configValues.merge(values), nil
+}
+
+// loadConfig returns the effective config: the user-level config merged
+// below the config in root, the repository root. Relative paths are
+// resolved against root.
+func loadConfig(root string) (Config, error) {
+	userCfg, err := loadUserConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	repoCfg, err := loadRepoConfig(root)
+	if err != nil {
+		return Config{}, err
+	}
+	return userCfg.merge(repoCfg).resolvePaths(root), nil
+}
+
+// loadRepoConfig reads .gtauto.yml (or .gtauto.yaml) from the repository root.
+// A missing file is not an error and yields an empty Config.
+func loadRepoConfig(root string) (Config, error) {
+	for _, name := range configFileNames {
+		cfg, err := readConfigFile(filepath.Join(root, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return cfg, err
+	}
+	return Config{}, nil
+}
+
+// loadUserConfig reads the personal config from userConfigPath.
+// A missing file is not an error and yields an empty Config.
+func loadUserConfig() (Config, error) {
+	path, err := userConfigPath()
+	if err != nil {
+		return Config{}, nil
+	}
+	cfg, err := readConfigFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	return cfg, err
+}
+
+// userConfigPath returns $XDG_CONFIG_HOME/gtauto/config.yml, falling back
+// to ~/.config/gtauto/config.yml when XDG_CONFIG_HOME is unset or relative
+func userConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" || !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gtauto", "config.yml"), nil
+}
+
+// readConfigFile parses a single YAML config file
+func readConfigFile(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var cfg Config
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, configParseError(path, err)
+	}
+	return cfg, nil
+}
+
+// yamlLineRegex matches the line number yaml.v3 puts in its errors
+var yamlLineRegex = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// configParseError rewrites a YAML decoding error of the config file at
+// path as "path:line: problem", one line per problem, so that editors and
+// terminals can jump to the offending line
+func configParseError(path string, err error) error {
+	var problems []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		problems = typeErr.Errors
+	} else {
+		problems = []string{err.Error()}
+	}
+
+	lines := make([]string, len(problems))
+	for i, problem := range problems {
+		if match := yamlLineRegex.FindStringSubmatch(problem); match != nil {
+			lines[i] = fmt.Sprintf("%s:%s: %s", path, match[1], match[2])
+		} else {
+			lines[i] = fmt.Sprintf("%s: %s", path, strings.TrimPrefix(problem, "yaml: "))
+		}
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// resolvePaths applies configValues.resolvePaths to the defaults and every profile
+func (c Config) resolvePaths(dir string) Config {
+	c.configValues = c.configValues.resolvePaths(dir)
+	for name, values := range c.Profiles {
+		c.Profiles[name] = values.resolvePaths(dir)
+	}
+	return c
+}
+
+// applyConfig sets every flag that was not given on the command line to
+// the corresponding config value
+func applyConfig(values configValues) error {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for name, value := range values.flagValues() {
+		if explicit[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("invalid config value for %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "defaults and profiles",
+			content: `changelog: docs/CHANGELOG.md
+force: false
+profiles:
+  beta:
+    force: true
+`,
+			wantErr: false,
+		},
+		{
+			name:    "message prefixes",
+			content: "error_prefix: \"✗ \"\nwarning_prefix: \"⚠ \"\nsuccess_prefix: \"✓ \"\n",
+			wantErr: false,
+		},
+		{
+			name:    "empty file",
+			content: "",
+			wantErr: false,
+		},
+		{
+			name:    "unknown key",
+			content: "changelgo: CHANGELOG.md\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed yaml",
+			content: "changelog: [unterminated\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".gtauto.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test config: %v", err)
+			}
+
+			_, err := readConfigFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gtauto.yml")
+	content := `changelog: CHANGELOG.md
+force: false
+profiles:
+  beta:
+    force: true
+  nightly:
+    changelog: NIGHTLY.md
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "no profile uses top-level defaults",
+			profile: "",
+			want:    map[string]string{"changelog": "CHANGELOG.md", "force": "false"},
+		},
+		{
+			name:    "profile overrides defaults",
+			profile: "beta",
+			want:    map[string]string{"changelog": "CHANGELOG.md", "force": "true"},
+		},
+		{
+			name:    "profile keeps unset defaults",
+			profile: "nightly",
+			want:    map[string]string{"changelog": "NIGHTLY.md", "force": "false"},
+		},
+		{
+			name:    "unknown profile lists available",
+			profile: "stable",
+			wantErr: "available: beta, nightly",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := cfg.resolve(tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("resolve() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+
+			got := values.flagValues()
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("resolve() %s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigResolvePaths(t *testing.T) {
+	changelog := "docs/CHANGELOG.md"
+	cfg := Config{configValues: configValues{Changelog: &changelog}}
+
+	got := cfg.resolvePaths("/repo")
+	want := filepath.Join("/repo", "docs", "CHANGELOG.md")
+	if *got.Changelog != want {
+		t.Errorf("resolvePaths() changelog = %q, want %q", *got.Changelog, want)
+	}
+}
+
+func TestConfigMerge(t *testing.T) {
+	userChangelog := "USER.md"
+	repoChangelog := "REPO.md"
+	userForce := true
+	repoForce := false
+
+	user := Config{
+		configValues: configValues{Changelog: &userChangelog, Force: &userForce},
+		Profiles: map[string]configValues{
+			"beta":    {Changelog: &userChangelog},
+			"nightly": {Force: &userForce},
+		},
+	}
+	repo := Config{
+		configValues: configValues{Changelog: &repoChangelog},
+		Profiles: map[string]configValues{
+			"beta": {Force: &repoForce},
+		},
+	}
+
+	merged := user.merge(repo)
+
+	tests := []struct {
+		name    string
+		profile string
+		want    map[string]string
+	}{
+		{
+			name:    "repo overrides user, unset repo keeps user",
+			profile: "",
+			want:    map[string]string{"changelog": "REPO.md", "force": "true"},
+		},
+		{
+			name:    "profiles from both layers are merged",
+			profile: "beta",
+			want:    map[string]string{"changelog": "USER.md", "force": "false"},
+		},
+		{
+			name:    "user-only profile is available",
+			profile: "nightly",
+			want:    map[string]string{"changelog": "REPO.md", "force": "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := merged.resolve(tt.profile)
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			got := values.flagValues()
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("merged %s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadUserConfig(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	cfg, err := loadUserConfig()
+	if err != nil {
+		t.Fatalf("loadUserConfig() without file error = %v", err)
+	}
+	if cfg.Changelog != nil {
+		t.Errorf("loadUserConfig() without file changelog = %q, want unset", *cfg.Changelog)
+	}
+
+	dir := filepath.Join(configHome, "gtauto")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte("force: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+
+	cfg, err = loadUserConfig()
+	if err != nil {
+		t.Fatalf("loadUserConfig() error = %v", err)
+	}
+	if cfg.Force == nil || !*cfg.Force {
+		t.Errorf("loadUserConfig() force = %v, want true", cfg.Force)
+	}
+}
+
+func TestUserConfigPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("No home directory available")
+	}
+
+	tests := []struct {
+		name       string
+		configHome string
+		want       string
+	}{
+		{
+			name:       "XDG_CONFIG_HOME set",
+			configHome: filepath.Join(home, "xdg"),
+			want:       filepath.Join(home, "xdg", "gtauto", "config.yml"),
+		},
+		{
+			name:       "XDG_CONFIG_HOME unset",
+			configHome: "",
+			want:       filepath.Join(home, ".config", "gtauto", "config.yml"),
+		},
+		{
+			name:       "relative XDG_CONFIG_HOME is ignored",
+			configHome: "relative",
+			want:       filepath.Join(home, ".config", "gtauto", "config.yml"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", tt.configHome)
+			got, err := userConfigPath()
+			if err != nil {
+				t.Fatalf("userConfigPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("userConfigPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigResolveBackslashPaths(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "repo")
+	tests := []struct {
+		name      string
+		changelog string
+		want      string
+	}{
+		{"backslashes", `docs\CHANGELOG.md`, filepath.Join(root, "docs", "CHANGELOG.md")},
+		{"mixed separators", `docs\release/CHANGELOG.md`, filepath.Join(root, "docs", "release", "CHANGELOG.md")},
+		{"dot segments", `.\docs\..\CHANGELOG.md`, filepath.Join(root, "CHANGELOG.md")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changelog := tt.changelog
+			got := configValues{Changelog: &changelog}.resolvePaths(root)
+			if *got.Changelog != tt.want {
+				t.Errorf("resolvePaths(%q) = %q, want %q", tt.changelog, *got.Changelog, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowsChangelogPath(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("backslash is only a path separator on Windows")
+	}
+	dir := initTestRepo(t)
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatalf("Failed to create docs directory: %v", err)
+	}
+	content := "# Changelog\n\n## [v1.0.0]\n- Initial release\n"
+	if err := os.WriteFile(filepath.Join(dir, "docs", "CHANGELOG.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+
+	root, err := gitRoot()
+	if err != nil {
+		t.Fatalf("gitRoot() error = %v", err)
+	}
+	if strings.Contains(root, "/") {
+		t.Errorf("gitRoot() = %q, want native separators", root)
+	}
+	if _, err := extractChangelogEntry("v1.0.0", `docs\CHANGELOG.md`, extractOptions{}); err != nil {
+		t.Errorf("extractChangelogEntry() with a backslash path error = %v", err)
+	}
+}
+
+func TestReadConfigFileErrorLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax error", "changelog: CHANGELOG.md\nforce: true\n  remote: upstream\n", ":3: mapping values are not allowed in this context"},
+		{"unknown key", "changelog: CHANGELOG.md\n\nrmote: upstream\n", ":3: field rmote not found"},
+		{"wrong type", "sign: sometimes\n", ":1: cannot unmarshal !!str `sometimes` into bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".gtauto.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test config: %v", err)
+			}
+
+			_, err := readConfigFile(path)
+			if err == nil || !strings.HasPrefix(err.Error(), path+tt.want) {
+				t.Errorf("readConfigFile() error = %v, want prefix %q", err, path+tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigRemoteAndSign(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gtauto.yml")
+	if err := os.WriteFile(path, []byte("remote: upstream\nsign: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile() error = %v", err)
+	}
+	want := map[string]string{"remote": "upstream", "sign": "true"}
+	if got := cfg.configValues.flagValues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("flagValues() = %v, want %v", got, want)
+	}
+}
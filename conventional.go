@@ -0,0 +1,85 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// breakingChange is the parseConventionalCommits key of breaking changes,
+// marked with "!" after the type or a BREAKING CHANGE footer
+const breakingChange = "BREAKING CHANGE"
+
+// conventionalSections are the changelog subsections rendered from
+// conventional commits, in order; other commit types are left out
+var conventionalSections = []struct {
+	key, heading string
+}{
+	{breakingChange, "Breaking Changes"},
+	{"feat", "Added"},
+	{"fix", "Fixed"},
+	{"perf", "Performance"},
+}
+
+// conventionalSubjectRegex matches a Conventional Commits subject such as
+// "feat(parser)!: support arrays", capturing the type, scope, "!" marker
+// and description
+var conventionalSubjectRegex = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?:\s+(.+)$`)
+
+// breakingFooterRegex matches a BREAKING CHANGE footer, capturing its text
+var breakingFooterRegex = regexp.MustCompile(`^BREAKING[ -]CHANGE:\s*(.*)$`)
+
+// parseConventionalCommits groups the descriptions of the conventional
+// commits among messages by lower-cased type, keeping their order. Breaking
+// changes go under breakingChange instead, described by their footer if it
+// has text. A scope is put in front of the description, as "**scope:** text".
+// Messages that do not follow the convention are skipped.
+func parseConventionalCommits(messages []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, message := range messages {
+		lines := strings.Split(strings.TrimSpace(message), "\n")
+		match := conventionalSubjectRegex.FindStringSubmatch(strings.TrimSpace(lines[0]))
+		if match == nil {
+			continue
+		}
+		kind, scope, breaking, description := strings.ToLower(match[1]), match[2], match[3] != "", strings.TrimSpace(match[4])
+
+		for _, line := range lines[1:] {
+			if footer := breakingFooterRegex.FindStringSubmatch(strings.TrimSpace(line)); footer != nil {
+				breaking = true
+				if text := strings.TrimSpace(footer[1]); text != "" {
+					description = text
+				}
+				break
+			}
+		}
+		if breaking {
+			kind = breakingChange
+		}
+		if scope = strings.TrimSpace(scope); scope != "" {
+			description = "**" + scope + ":** " + description
+		}
+		groups[kind] = append(groups[kind], description)
+	}
+	return groups
+}
+
+// renderConventionalEntry renders groups from parseConventionalCommits as a
+// changelog entry under header, with a subsection of list items for each of
+// conventionalSections that has commits. It returns "" if none has any.
+func renderConventionalEntry(header string, groups map[string][]string, opts extractOptions) string {
+	var b strings.Builder
+	for _, section := range conventionalSections {
+		items := groups[section.key]
+		if len(items) == 0 {
+			continue
+		}
+		b.WriteString("\n" + opts.heading() + "# " + section.heading + "\n")
+		for _, item := range items {
+			b.WriteString("- " + item + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return header + "\n" + strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConventionalCommits(t *testing.T) {
+	messages := []string{
+		"feat(parser): support arrays",
+		"fix: handle empty input\n\nCloses #12",
+		"Fix: capitalized type",
+		"perf: cache compiled patterns",
+		"feat!: drop the v1 config format",
+		"refactor: split main.go\n\nBREAKING CHANGE: the --old flag is gone",
+		"fix(api): rename field\n\nBREAKING-CHANGE:",
+		"docs: update README",
+		"Merge branch 'main' into feature",
+		"not conventional: has a space in the type",
+	}
+
+	want := map[string][]string{
+		"feat":         {"**parser:** support arrays"},
+		"fix":          {"handle empty input", "capitalized type"},
+		"perf":         {"cache compiled patterns"},
+		breakingChange: {"drop the v1 config format", "the --old flag is gone", "**api:** rename field"},
+		"docs":         {"update README"},
+	}
+	if got := parseConventionalCommits(messages); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConventionalCommits() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderConventionalEntry(t *testing.T) {
+	groups := map[string][]string{
+		"fix":          {"handle empty input"},
+		"feat":         {"support arrays", "add --verbose"},
+		breakingChange: {"drop the v1 config format"},
+		"docs":         {"update README"},
+	}
+	opts := extractOptions{headingLevel: defaultHeadingLevel}
+
+	got := renderConventionalEntry("## [v1.2.0] - 2025-09-01", groups, opts)
+	want := `## [v1.2.0] - 2025-09-01
+
+### Breaking Changes
+- drop the v1 config format
+
+### Added
+- support arrays
+- add --verbose
+
+### Fixed
+- handle empty input`
+	if got != want {
+		t.Errorf("renderConventionalEntry() = %q, want %q", got, want)
+	}
+
+	if got := renderConventionalEntry("## v1.2.1", map[string][]string{"chore": {"bump deps"}}, opts); got != "" {
+		t.Errorf("renderConventionalEntry() without rendered types = %q, want empty", got)
+	}
+}
+
+func TestBuildConventional(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "v1.0.0")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "fix: handle empty input")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "feat(cli): add --conventional", "-m", "BREAKING CHANGE: --from-git-log output changed")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "chore: tidy up")
+
+	builder := messageBuilder{
+		changelogFile: "CHANGELOG.md",
+		extract:       extractOptions{headingLevel: defaultHeadingLevel},
+		conventional:  true,
+		releaseDate:   "2025-09-01",
+	}
+	got, found, err := builder.build("v1.1.0", "HEAD")
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+	want := "## [v1.1.0] - 2025-09-01\n\n### Breaking Changes\n- **cli:** --from-git-log output changed\n\n### Fixed\n- handle empty input"
+	if got != want || found {
+		t.Errorf("build() = %q, %v, want %q, false", got, found, want)
+	}
+
+	// Nothing to render keeps the generic message
+	got, _, err = builder.build("v1.0.0", "v1.0.0")
+	if err != nil || got != "Release v1.0.0" {
+		t.Errorf("build() without conventional commits = %q, %v, want %q", got, err, "Release v1.0.0")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runDelete deletes the local tag tagName and, if remote is set, the tag of
+// that name on remote. Unless force is set the user confirms first. Each
+// deletion is reported; the error lists the ones that failed.
+func runDelete(tagName, remote string, force, dryRun bool) error {
+	local := tagExists(tagName)
+	if !local && remote == "" {
+		return fmt.Errorf("tag '%s' does not exist", tagName)
+	}
+	if !local {
+		printWarning(fmt.Sprintf("There is no local tag '%s', deleting it from '%s' only", tagName, remote))
+	}
+
+	var commands [][]string
+	if local {
+		commands = append(commands, []string{"git", "tag", "-d", tagName})
+	}
+	if remote != "" {
+		commands = append(commands, []string{"git", "push", remote, ":refs/tags/" + tagName})
+	}
+	if dryRun {
+		for _, command := range commands {
+			printSuccess("Would run: " + formatCommand(command...))
+		}
+		printSuccess("Dry run: no changes made")
+		return nil
+	}
+
+	if !force {
+		question := fmt.Sprintf("Delete the local tag '%s'?", tagName)
+		switch {
+		case local && remote != "":
+			question = fmt.Sprintf("Delete tag '%s' locally and from '%s'?", tagName, remote)
+		case remote != "":
+			question = fmt.Sprintf("Delete tag '%s' from '%s'?", tagName, remote)
+		}
+		ok, err := confirm(question)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Operation cancelled")
+			return nil
+		}
+	}
+
+	var failed []string
+	if local {
+		if err := deleteTag(tagName); err != nil {
+			printWarning(fmt.Sprintf("Failed to delete the local tag '%s': %v", tagName, err))
+			failed = append(failed, "local")
+		} else {
+			printSuccess(fmt.Sprintf("✓ Deleted local tag '%s'", tagName))
+		}
+	}
+	if remote != "" {
+		if err := deleteRemoteTag(remote, tagName); err != nil {
+			printWarning(fmt.Sprintf("Failed to delete tag '%s' from '%s': %v", tagName, remote, err))
+			failed = append(failed, remote)
+		} else {
+			printSuccess(fmt.Sprintf("✓ Deleted tag '%s' from '%s'", tagName, remote))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not delete tag '%s' from: %s", tagName, strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDelete(t *testing.T) {
+	initTestRepo(t)
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	gitCmd(t, "init", "-q", "--bare", remoteDir)
+	gitCmd(t, "remote", "add", "upstream", remoteDir)
+	for _, name := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		gitCmd(t, "tag", name)
+		gitCmd(t, "push", "-q", "upstream", "refs/tags/"+name)
+	}
+	remoteHas := func(name string) bool {
+		return gitCmd(t, "ls-remote", "--tags", "upstream", name) != ""
+	}
+
+	// A dry run changes nothing
+	if err := runDelete("v1.0.0", "upstream", true, true); err != nil {
+		t.Fatalf("runDelete() dry run error = %v", err)
+	}
+	if !tagExists("v1.0.0") || !remoteHas("v1.0.0") {
+		t.Error("runDelete() dry run deleted the tag")
+	}
+
+	// Without a remote only the local tag goes
+	if err := runDelete("v1.0.0", "", true, false); err != nil {
+		t.Fatalf("runDelete() local error = %v", err)
+	}
+	if tagExists("v1.0.0") || !remoteHas("v1.0.0") {
+		t.Error("runDelete() without a remote should delete only the local tag")
+	}
+
+	if err := runDelete("v1.1.0", "upstream", true, false); err != nil {
+		t.Fatalf("runDelete() with remote error = %v", err)
+	}
+	if tagExists("v1.1.0") || remoteHas("v1.1.0") {
+		t.Error("runDelete() with a remote should delete both tags")
+	}
+
+	// A tag that is only on the remote is still deleted there
+	if err := runDelete("v1.0.0", "upstream", true, false); err != nil {
+		t.Fatalf("runDelete() remote only error = %v", err)
+	}
+	if remoteHas("v1.0.0") {
+		t.Error("runDelete() did not delete the remote-only tag")
+	}
+
+	if err := runDelete("v9.9.9", "", true, false); err == nil {
+		t.Error("runDelete() of a missing tag returned nil error")
+	}
+
+	// A failed remote deletion is reported after the local one succeeded
+	err := runDelete("v1.2.0", "missing", true, false)
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("runDelete() with an unknown remote error = %v, want it to name the remote", err)
+	}
+	if tagExists("v1.2.0") {
+		t.Error("runDelete() kept the local tag after the remote deletion failed")
+	}
+}
+
+func TestRunDeleteWithoutTerminal(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "v1.0.0")
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	_, _ = w.WriteString("y\n")
+	_ = w.Close()
+	originalStdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = originalStdin
+		_ = r.Close()
+	})
+
+	if err := runDelete("v1.0.0", "", false, false); err == nil || !strings.Contains(err.Error(), "not a terminal") {
+		t.Errorf("runDelete() without --force or a terminal error = %v, want stdin is not a terminal", err)
+	}
+	if !tagExists("v1.0.0") {
+		t.Error("runDelete() deleted the tag without confirmation")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffHunk is one hunk of a unified diff. Lines carry their " ", "-" or "+"
+// prefix.
+type diffHunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// diffLines returns the edit script turning a into b as prefixed lines,
+// based on their longest common subsequence
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, "-"+a[i])
+			i++
+		default:
+			ops = append(ops, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, "+"+b[j])
+	}
+	return ops
+}
+
+// unifiedDiff groups the changes between the lines of a and b into hunks
+// with diffContext lines of context
+func unifiedDiff(a, b []string) []diffHunk {
+	ops := diffLines(a, b)
+
+	// Merge the context windows around changes into [start, end) op ranges
+	var ranges [][2]int
+	for i, op := range ops {
+		if op[0] == ' ' {
+			continue
+		}
+		start, end := max(0, i-diffContext), min(len(ops), i+diffContext+1)
+		if n := len(ranges); n > 0 && start <= ranges[n-1][1] {
+			ranges[n-1][1] = end
+		} else {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+
+	var hunks []diffHunk
+	oldLine, newLine, pos := 1, 1, 0
+	for _, r := range ranges {
+		for ; pos < r[0]; pos++ {
+			oldLine, newLine = advance(ops[pos], oldLine, newLine)
+		}
+		hunk := diffHunk{OldStart: oldLine, NewStart: newLine, Lines: ops[r[0]:r[1]]}
+		for ; pos < r[1]; pos++ {
+			if ops[pos][0] != '+' {
+				hunk.OldLines++
+			}
+			if ops[pos][0] != '-' {
+				hunk.NewLines++
+			}
+			oldLine, newLine = advance(ops[pos], oldLine, newLine)
+		}
+		// An empty side is numbered after the line preceding the hunk
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+// advance moves the old and new line numbers past op
+func advance(op string, oldLine, newLine int) (int, int) {
+	if op[0] != '+' {
+		oldLine++
+	}
+	if op[0] != '-' {
+		newLine++
+	}
+	return oldLine, newLine
+}
+
+// writeUnifiedDiff writes hunks as a unified diff between from and to
+func writeUnifiedDiff(w io.Writer, from, to string, hunks []diffHunk) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		for _, line := range hunk.Lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// compareVersions returns the two versions of --compare, given either as
+// "<tagA>..<tagB>" or as "<tagA>" followed by the argument "<tagB>". Flags
+// after the positional argument are not parsed, so any further argument is
+// an error rather than silently ignored.
+func compareVersions(value string, args []string) (from, to string, err error) {
+	const usage = "use --compare <tagA>..<tagB>, or --compare <tagA> <tagB> with every other option before --compare"
+	from, to, isRange := strings.Cut(value, "..")
+	if !isRange && len(args) == 1 {
+		return value, args[0], nil
+	}
+	switch {
+	case isRange && len(args) > 0:
+		return "", "", fmt.Errorf("unexpected arguments after --compare %s: %s; %s", value, strings.Join(args, " "), usage)
+	case len(args) > 1:
+		return "", "", fmt.Errorf("unexpected arguments after --compare %s %s: %s; %s", value, args[0], strings.Join(args[1:], " "), usage)
+	case isRange && from != "" && to != "":
+		return from, to, nil
+	}
+	return "", "", fmt.Errorf("--compare needs two versions; %s", usage)
+}
+
+// compareFormats lists the supported --format values for --compare
+var compareFormats = []string{"text", "json"}
+
+// runCompare writes the diff between the changelog entries of two versions
+// to output. The versions only need to exist in the changelog.
+func runCompare(from, to, changelogFile string, opts extractOptions, format, output string) error {
+	if !contains(compareFormats, format) {
+		return fmt.Errorf("unknown format '%s' for --compare (available: %s)", format, strings.Join(compareFormats, ", "))
+	}
+
+	var entries [2][]string
+	for i, version := range []string{from, to} {
+		entry, err := extractChangelogEntry(version, changelogFile, opts)
+		if err != nil {
+			return err
+		}
+		entries[i] = strings.Split(entry, "\n")
+	}
+	hunks := unifiedDiff(entries[0], entries[1])
+
+	w, err := openOutput(output)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		if hunks == nil {
+			hunks = []diffHunk{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(struct {
+			From  string     `json:"from"`
+			To    string     `json:"to"`
+			Hunks []diffHunk `json:"hunks"`
+		}{from, to, hunks})
+	} else {
+		err = writeUnifiedDiff(w, from, to, hunks)
+	}
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []diffHunk
+	}{
+		{
+			name: "identical",
+			a:    "## [v1.0.0]\n- Feature",
+			b:    "## [v1.0.0]\n- Feature",
+			want: nil,
+		},
+		{
+			name: "changed line",
+			a:    "## [v1.0.0]\n### Added\n- Feature",
+			b:    "## [v1.1.0]\n### Added\n- Feature",
+			want: []diffHunk{{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Lines: []string{
+				"-## [v1.0.0]", "+## [v1.1.0]", " ### Added", " - Feature",
+			}}},
+		},
+		{
+			name: "distant changes form separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10",
+			b:    "0\n1\n2\n3\n4\n5\n6\n7\n8\n9",
+			want: []diffHunk{
+				{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 4, Lines: []string{"+0", " 1", " 2", " 3"}},
+				{OldStart: 7, OldLines: 4, NewStart: 8, NewLines: 3, Lines: []string{" 7", " 8", " 9", "-10"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff(strings.Split(tt.a, "\n"), strings.Split(tt.b, "\n"))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unifiedDiff() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	dir := t.TempDir()
+	changelog := filepath.Join(dir, "CHANGELOG.md")
+	content := `# Changelog
+
+## [v1.1.0] - 2025-08-27
+
+### Fixed
+- Crash on start
+
+## [v1.0.1] - 2025-08-26
+
+### Fixed
+- Crash on start
+- Typo in help
+`
+	if err := os.WriteFile(changelog, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+	opts := extractOptions{headingLevel: defaultHeadingLevel}
+
+	textPath := filepath.Join(dir, "diff.txt")
+	if err := runCompare("v1.0.1", "v1.1.0", changelog, opts, "text", textPath); err != nil {
+		t.Fatalf("runCompare() error = %v", err)
+	}
+	got, err := os.ReadFile(textPath)
+	if err != nil {
+		t.Fatalf("Failed to read diff: %v", err)
+	}
+	want := `--- v1.0.1
++++ v1.1.0
+@@ -1,5 +1,4 @@
+-## [v1.0.1] - 2025-08-26
++## [v1.1.0] - 2025-08-27
+ 
+ ### Fixed
+ - Crash on start
+-- Typo in help
+`
+	if string(got) != want {
+		t.Errorf("text diff =\n%s\nwant\n%s", got, want)
+	}
+
+	jsonPath := filepath.Join(dir, "diff.json")
+	if err := runCompare("v1.0.1", "v1.1.0", changelog, opts, "json", jsonPath); err != nil {
+		t.Fatalf("runCompare() error = %v", err)
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to read diff: %v", err)
+	}
+	var decoded struct {
+		From  string     `json:"from"`
+		To    string     `json:"to"`
+		Hunks []diffHunk `json:"hunks"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	if decoded.From != "v1.0.1" || decoded.To != "v1.1.0" || len(decoded.Hunks) != 1 || decoded.Hunks[0].OldLines != 5 {
+		t.Errorf("JSON diff = %+v", decoded)
+	}
+
+	if err := runCompare("v1.0.1", "v9.9.9", changelog, opts, "text", textPath); err == nil {
+		t.Error("runCompare() with a missing version expected error, got nil")
+	}
+	if err := runCompare("v1.0.1", "v1.1.0", changelog, opts, "csv", textPath); err == nil {
+		t.Error("runCompare() with csv format expected error, got nil")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		args     []string
+		wantFrom string
+		wantTo   string
+		wantErr  bool
+	}{
+		{"range", "v1.0.1..v1.1.0", nil, "v1.0.1", "v1.1.0", false},
+		{"two arguments", "v1.0.1", []string{"v1.1.0"}, "v1.0.1", "v1.1.0", false},
+		{"one version", "v1.0.1", nil, "", "", true},
+		{"open range", "v1.0.1..", nil, "", "", true},
+		// Flags after the second version are not parsed
+		{"trailing flags", "v1.0.1", []string{"v1.1.0", "--format", "json"}, "", "", true},
+		{"range with arguments", "v1.0.1..v1.1.0", []string{"extra"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := compareVersions(tt.value, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compareVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("compareVersions() = %q, %q, want %q, %q", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
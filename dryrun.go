@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+)
+
+// dryRunPlan is the --dry-run --format json description of what a run
+// would do
+type dryRunPlan struct {
+	Tag    string `json:"tag"`
+	Commit string `json:"commit"`
+	// Overwrite is set when an existing tag would be replaced
+	Overwrite      bool   `json:"overwrite"`
+	ChangelogFound bool   `json:"changelog_found"`
+	Message        string `json:"message"`
+	// Commands are the git invocations that would run, in order
+	Commands [][]string `json:"commands"`
+	// Flags are the flags set on the command line or by the config
+	Flags map[string]string `json:"flags"`
+}
+
+// setFlags returns the value of every flag that was set on fs
+func setFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	return flags
+}
+
+// writeDryRunPlan writes plan to w as indented JSON
+func writeDryRunPlan(w io.Writer, plan dryRunPlan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestWriteDryRunPlan(t *testing.T) {
+	plan := dryRunPlan{
+		Tag:            "v1.0.0",
+		Commit:         "0123456789abcdef0123456789abcdef01234567",
+		Overwrite:      true,
+		ChangelogFound: true,
+		Message:        "## [1.0.0]\n\n- Added feature",
+		Commands: [][]string{
+			{"git", "tag", "-d", "v1.0.0"},
+			{"git", "tag", "-a", "v1.0.0", "-m", "## [1.0.0]\n\n- Added feature"},
+			{"git", "push", "origin", "refs/tags/v1.0.0"},
+		},
+		Flags: map[string]string{"dry-run": "true", "push": "true"},
+	}
+
+	var b bytes.Buffer
+	if err := writeDryRunPlan(&b, plan); err != nil {
+		t.Fatalf("writeDryRunPlan() error = %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, b.String())
+	}
+	want := map[string]interface{}{
+		"tag":             "v1.0.0",
+		"commit":          "0123456789abcdef0123456789abcdef01234567",
+		"overwrite":       true,
+		"changelog_found": true,
+		"message":         "## [1.0.0]\n\n- Added feature",
+		"commands": []interface{}{
+			[]interface{}{"git", "tag", "-d", "v1.0.0"},
+			[]interface{}{"git", "tag", "-a", "v1.0.0", "-m", "## [1.0.0]\n\n- Added feature"},
+			[]interface{}{"git", "push", "origin", "refs/tags/v1.0.0"},
+		},
+		"flags": map[string]interface{}{"dry-run": "true", "push": "true"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plan = %#v, want %#v", got, want)
+	}
+}
+
+func TestSetFlags(t *testing.T) {
+	fs := flag.NewFlagSet("gtauto", flag.ContinueOnError)
+	fs.String("tag", "", "")
+	fs.Bool("dry-run", false, "")
+	fs.Bool("push", false, "")
+	fs.String("remote", "origin", "")
+	if err := fs.Parse([]string{"--tag", "v1.0.0", "--dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"tag": "v1.0.0", "dry-run": "true"}
+	if got := setFlags(fs); !reflect.DeepEqual(got, want) {
+		t.Errorf("setFlags() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterTags are the taxonomy tags given to release notes pages
+var frontMatterTags = []string{"release"}
+
+// frontMatterFields is the YAML front matter of a release notes page
+type frontMatterFields struct {
+	Title string   `yaml:"title"`
+	Date  string   `yaml:"date"`
+	Tags  []string `yaml:"tags"`
+}
+
+// sectionDate returns the date of the changelog section for version, which
+// may be written with or without the "v" prefix, or "" if it has none
+func sectionDate(sections []changelogSection, version string) string {
+	candidates := tagCandidates(version)
+	for _, section := range sections {
+		if contains(candidates, section.Version) {
+			return section.Date
+		}
+	}
+	return ""
+}
+
+// withFrontMatter wraps body in YAML front matter for static site generators
+// such as Hugo and Jekyll. An empty date is replaced with today's.
+func withFrontMatter(title, date, body string, today time.Time) (string, error) {
+	if date == "" {
+		date = today.Format(sectionDateLayout)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(frontMatterFields{Title: title, Date: date, Tags: frontMatterTags}); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(strings.TrimRight(body, "\n"))
+	b.WriteString("\n")
+	return b.String(), nil
+}
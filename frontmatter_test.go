@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWithFrontMatter(t *testing.T) {
+	today := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		date     string
+		wantDate string
+	}{
+		{"section date", "2025-08-27", "2025-08-27"},
+		{"no section date", "", "2025-09-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withFrontMatter("v1.0.1", tt.date, "## [v1.0.1]\n\n- Fix\n", today)
+			if err != nil {
+				t.Fatalf("withFrontMatter() error = %v", err)
+			}
+
+			if !strings.HasPrefix(got, "---\n") {
+				t.Fatalf("withFrontMatter() = %q, want an opening --- delimiter", got)
+			}
+			header, body, ok := strings.Cut(strings.TrimPrefix(got, "---\n"), "\n---\n")
+			if !ok {
+				t.Fatalf("withFrontMatter() = %q, want a closing --- delimiter", got)
+			}
+			if body != "\n## [v1.0.1]\n\n- Fix\n" {
+				t.Errorf("body = %q, want the section after a blank line", body)
+			}
+
+			var fields frontMatterFields
+			if err := yaml.Unmarshal([]byte(header), &fields); err != nil {
+				t.Fatalf("front matter is not valid YAML: %v\n%s", err, header)
+			}
+			if fields.Title != "v1.0.1" || fields.Date != tt.wantDate || len(fields.Tags) != 1 || fields.Tags[0] != "release" {
+				t.Errorf("front matter = %+v, want title v1.0.1, date %s, tags [release]", fields, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestSectionDate(t *testing.T) {
+	sections := []changelogSection{
+		{Version: "v1.1.0", Date: "2025-09-01"},
+		{Version: "1.0.0", Date: "2025-08-01"},
+		{Version: "0.9.0"},
+	}
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"v1.1.0", "2025-09-01"},
+		{"v1.0.0", "2025-08-01"},
+		{"v0.9.0", ""},
+		{"v2.0.0", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sectionDate(sections, tt.version); got != tt.want {
+			t.Errorf("sectionDate(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verbose makes runCommand log every git command line to stderr, and the
+// command's output when it fails. It is set with --verbose.
+var verbose bool
+
+// runCommand runs the git command cmd, feeding stdin to the process when it
+// is non-empty, and returns its standard output and standard error
+func runCommand(cmd *exec.Cmd, stdin string) ([]byte, string, error) {
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if verbose {
+		fmt.Fprintln(os.Stderr, "+ "+formatCommand(cmd.Args...))
+	}
+	err := cmd.Run()
+	if err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "  %v\n", err)
+		for _, stream := range []struct {
+			name string
+			text string
+		}{{"stdout", stdout.String()}, {"stderr", stderr.String()}} {
+			text := strings.TrimRight(stream.text, "\n")
+			if text == "" {
+				continue
+			}
+			lines := strings.Split(text, "\n")
+			for i := 1; i < len(lines); i++ {
+				if lines[i] != "" {
+					lines[i] = "    " + lines[i]
+				}
+			}
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", stream.name, strings.Join(lines, "\n"))
+		}
+	}
+	return stdout.Bytes(), stderr.String(), err
+}
+
+// gitExec runs git with the given arguments, feeding stdin to the process
+// when it is non-empty and adding env to its environment, and returns its
+// standard output. On failure the error includes git's standard error.
+// Tests replace it with a fake.
+var gitExec = func(stdin string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	output, stderr, err := runCommand(cmd, stdin)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return output, fmt.Errorf("%w: %s", err, msg)
+		}
+		return output, err
+	}
+	return output, nil
+}
+
+// runGit runs git with the given arguments and returns its standard output
+func runGit(args ...string) ([]byte, error) {
+	return gitExec("", nil, args...)
+}
+
+var gitVersionRegex = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// gitVersionAtLeast reports whether the installed git is at least major.minor
+func gitVersionAtLeast(major, minor int) (bool, error) {
+	output, err := runGit("version")
+	if err != nil {
+		return false, err
+	}
+
+	// Output looks like "git version 2.39.5" or "git version 2.37.1 (Apple Git-137.1)"
+	match := gitVersionRegex.FindStringSubmatch(string(output))
+	if match == nil {
+		return false, fmt.Errorf("unrecognized git version: %s", strings.TrimSpace(string(output)))
+	}
+	gotMajor, _ := strconv.Atoi(match[1])
+	gotMinor, _ := strconv.Atoi(match[2])
+
+	if gotMajor != major {
+		return gotMajor > major, nil
+	}
+	return gotMinor >= minor, nil
+}
+
+// gitRoot returns the top-level directory of the working tree. git prints
+// it with forward slashes on every platform, so it is converted to a native
+// path.
+func gitRoot() (string, error) {
+	output, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(filepath.FromSlash(strings.TrimSpace(string(output)))), nil
+}
+
+// tagDetails describes an existing tag
+type tagDetails struct {
+	Name      string
+	Annotated bool
+	Signed    bool
+	// Commit is the commit the tag ultimately points to
+	Commit string
+	// Message is the annotation without its signature; empty for
+	// lightweight tags
+	Message string
+}
+
+// tagInfo looks up the type, signature, message and target commit of an
+// existing tag
+func tagInfo(tagName string) (tagDetails, error) {
+	format := "%(objecttype)%00%(*objectname)%00%(objectname)%00%(contents)%00%(contents:signature)"
+	output, err := runGit("for-each-ref", "--count=1", "--format="+format, "refs/tags/"+tagName)
+	if err != nil {
+		return tagDetails{}, err
+	}
+	fields := strings.SplitN(string(output), "\x00", 5)
+	if len(fields) < 5 {
+		return tagDetails{}, fmt.Errorf("tag '%s' not found", tagName)
+	}
+
+	signature := strings.TrimSuffix(fields[4], "\n")
+	info := tagDetails{
+		Name:      tagName,
+		Annotated: fields[0] == "tag",
+		Signed:    strings.TrimSpace(signature) != "",
+		Commit:    fields[2],
+	}
+	if info.Annotated {
+		info.Commit = fields[1]
+		info.Message = strings.TrimRight(strings.TrimSuffix(fields[3], signature), "\n")
+	}
+	return info, nil
+}
+
+// retagTarget returns the commit of the existing tag sourceTag, in full and
+// abbreviated, for --retag-from
+func retagTarget(sourceTag string) (commit, short string, err error) {
+	info, err := tagInfo(sourceTag)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot retag from '%s': %w", sourceTag, err)
+	}
+	short, err = shortCommit(info.Commit)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot resolve the commit of '%s': %w", sourceTag, err)
+	}
+	return info.Commit, short, nil
+}
+
+// cleanupMessage applies the cleanup git performs on tag messages, such as
+// dropping comment lines and surplus blank lines, so a message can be
+// compared with one read back from a tag
+func cleanupMessage(message string) (string, error) {
+	output, err := gitExec(message, nil, "stripspace", "--strip-comments")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// listAllTags returns the names of all tags sorted by name
+func listAllTags() ([]string, error) {
+	output, err := runGit("tag", "-l")
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			tags = append(tags, line)
+		}
+	}
+	return tags, nil
+}
+
+// describeBaseTag returns the nearest tag reachable from commit, i.e. the
+// "v1.2.0" of a "git describe --tags" result such as "v1.2.0-5-gabc123",
+// or "" if no tag is reachable yet
+func describeBaseTag(commit string) (string, error) {
+	reachable, err := runGit("tag", "--merged", commit)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(reachable)) == "" {
+		return "", nil
+	}
+	output, err := runGit("describe", "--tags", "--abbrev=0", commit)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// identRegex matches "git var GIT_COMMITTER_IDENT" output:
+// "Name <email> 1700000000 +0900"
+var identRegex = regexp.MustCompile(`^(.*) <(.*)> \d+ [+-]\d{4}$`)
+
+// committerIdentity returns the name and email git records as the tagger,
+// taken from the environment or user.name and user.email
+func committerIdentity() (string, string, error) {
+	output, err := runGit("var", "GIT_COMMITTER_IDENT")
+	if err != nil {
+		return "", "", err
+	}
+	match := identRegex.FindStringSubmatch(strings.TrimSpace(string(output)))
+	if match == nil || match[1] == "" || match[2] == "" {
+		return "", "", fmt.Errorf("incomplete identity: %s", strings.TrimSpace(string(output)))
+	}
+	return match[1], match[2], nil
+}
+
+// diffShortstat summarizes the changes between from and to, e.g.
+// "3 files changed, 10 insertions(+), 2 deletions(-)". An empty from
+// compares against the empty tree, covering the whole history of to.
+func diffShortstat(from, to string) (string, error) {
+	if from == "" {
+		// The empty tree's name depends on the repository's hash algorithm
+		output, err := runGit("hash-object", "-t", "tree", "--stdin")
+		if err != nil {
+			return "", err
+		}
+		from = strings.TrimSpace(string(output))
+	}
+	output, err := runGit("diff", "--shortstat", from, to)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// commitsSinceLastTag returns the subjects of the commits reachable from
+// commit but not from prevTag, newest first. An empty prevTag selects the
+// whole history of commit.
+func commitsSinceLastTag(prevTag, commit string) ([]string, error) {
+	revision := commit
+	if prevTag != "" {
+		revision = prevTag + ".." + commit
+	}
+	output, err := runGit("log", "--pretty=%s", revision, "--")
+	if err != nil {
+		return nil, err
+	}
+	var subjects []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
+
+// commitMessagesSince returns the full messages of the commits reachable
+// from commit but not from prevTag, newest first, like commitsSinceLastTag
+func commitMessagesSince(prevTag, commit string) ([]string, error) {
+	revision := commit
+	if prevTag != "" {
+		revision = prevTag + ".." + commit
+	}
+	output, err := runGit("log", "--pretty=%B%x00", revision, "--")
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, message := range strings.Split(string(output), "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// isWorkingTreeClean reports whether the working tree has no uncommitted
+// changes, untracked files included, and otherwise returns the paths that
+// git status lists
+func isWorkingTreeClean() (bool, []string, error) {
+	output, err := runGit("status", "--porcelain")
+	if err != nil {
+		return false, nil, err
+	}
+	var dirty []string
+	for _, line := range strings.Split(string(output), "\n") {
+		// Each line is a two-letter status, a space and the path
+		if len(line) > 3 {
+			dirty = append(dirty, line[3:])
+		}
+	}
+	return len(dirty) == 0, dirty, nil
+}
+
+// currentBranch returns the checked out branch name; it fails on a detached HEAD
+func currentBranch() (string, error) {
+	output, err := runGit("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// shortCommit resolves rev to an abbreviated commit hash
+func shortCommit(rev string) (string, error) {
+	output, err := runGit("rev-parse", "--short", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// recordRelease writes tagName and the time it was created to the local git
+// config under tagKey and dateKey, replacing any previous values
+func recordRelease(tagKey, dateKey, tagName string, when time.Time) error {
+	for _, entry := range [][2]string{
+		{tagKey, tagName},
+		{dateKey, when.Format(time.RFC3339)},
+	} {
+		if _, err := runGit("config", "--local", entry[0], entry[1]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", entry[0], err)
+		}
+	}
+	return nil
+}
+
+// upstreamBranch returns the upstream of the current branch, e.g.
+// "origin/main", or "" if it has none
+func upstreamBranch() (string, error) {
+	output, err := runGit("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		// A branch without upstream or a detached HEAD
+		if msg := err.Error(); strings.Contains(msg, "no upstream") || strings.Contains(msg, "does not point to a branch") {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// commitsBehind returns how many commits upstream has that HEAD lacks
+func commitsBehind(upstream string) (int, error) {
+	output, err := runGit("rev-list", "--count", "HEAD.."+upstream)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// resolveCommit returns the full hash of the commit rev points to
+func resolveCommit(rev string) (string, error) {
+	output, err := runGit("rev-parse", "--verify", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// isAncestor reports whether commit is reachable from ref, i.e. is an
+// ancestor of or identical to it
+func isAncestor(commit, ref string) (bool, error) {
+	_, err := runGit("merge-base", "--is-ancestor", commit, ref)
+	if err == nil {
+		return true, nil
+	}
+	// Exit status 1 means "not an ancestor"; anything else is a real error
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGit replaces gitExec for the duration of a test. The handler receives
+// the stdin and arguments of each git invocation and returns its output.
+func fakeGit(t testing.TB, handler func(stdin string, args []string) (string, error)) {
+	t.Helper()
+	original := gitExec
+	gitExec = func(stdin string, env []string, args ...string) ([]byte, error) {
+		output, err := handler(stdin, args)
+		return []byte(output), err
+	}
+	t.Cleanup(func() {
+		gitExec = original
+	})
+}
+
+func TestGitVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		err     error
+		want    bool
+		wantErr bool
+	}{
+		{"newer minor", "git version 2.39.5\n", nil, true, false},
+		{"same version", "git version 2.15.0\n", nil, true, false},
+		{"older minor", "git version 2.14.1\n", nil, false, false},
+		{"newer major", "git version 3.0.0\n", nil, true, false},
+		{"apple build", "git version 2.37.1 (Apple Git-137.1)\n", nil, true, false},
+		{"windows build", "git version 2.41.0.windows.1\n", nil, true, false},
+		{"unrecognized output", "git version unknown\n", nil, false, true},
+		{"git failure", "", errors.New("exit status 1"), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeGit(t, func(stdin string, args []string) (string, error) {
+				return tt.output, tt.err
+			})
+
+			got, err := gitVersionAtLeast(2, 15)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("gitVersionAtLeast() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("gitVersionAtLeast() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    tagDetails
+		wantErr bool
+	}{
+		{
+			name:   "annotated tag",
+			output: "tag\x00c0ffee\x00beef\x00Release v1.0.0\n\n- Added things\n\x00\n",
+			want:   tagDetails{Name: "v1.0.0", Annotated: true, Commit: "c0ffee", Message: "Release v1.0.0\n\n- Added things"},
+		},
+		{
+			name:   "signed tag",
+			output: "tag\x00c0ffee\x00beef\x00Release v1.0.0\n-----BEGIN PGP SIGNATURE-----\n...\n-----END PGP SIGNATURE-----\n\x00-----BEGIN PGP SIGNATURE-----\n...\n-----END PGP SIGNATURE-----\n\n",
+			want:   tagDetails{Name: "v1.0.0", Annotated: true, Signed: true, Commit: "c0ffee", Message: "Release v1.0.0"},
+		},
+		{
+			name:   "lightweight tag",
+			output: "commit\x00\x00c0ffee\x00initial\n\x00\n",
+			want:   tagDetails{Name: "v1.0.0", Commit: "c0ffee"},
+		},
+		{
+			name:    "missing tag",
+			output:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeGit(t, func(stdin string, args []string) (string, error) {
+				return tt.output, nil
+			})
+
+			got, err := tagInfo("v1.0.0")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tagInfo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("tagInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetagTarget(t *testing.T) {
+	initTestRepo(t)
+	changelog := "# Changelog\n\n## [v1.3.0] - 2025-09-01\n- Final release\n\n## [v1.3.0-rc.2] - 2025-08-25\n- Release candidate\n"
+	if err := os.WriteFile("CHANGELOG.md", []byte(changelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, "tag", "-a", "v1.3.0-rc.2", "-m", "rc notes")
+	rcCommit := gitCmd(t, "rev-parse", "HEAD")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "after the rc")
+
+	commit, short, err := retagTarget("v1.3.0-rc.2")
+	if err != nil {
+		t.Fatalf("retagTarget() error = %v", err)
+	}
+	if commit != rcCommit || !strings.HasPrefix(rcCommit, short) {
+		t.Fatalf("retagTarget() = %s, %s, want %s", commit, short, rcCommit)
+	}
+
+	// The promoted tag shares the rc's commit but gets its own entry
+	builder := messageBuilder{changelogFile: "CHANGELOG.md", extract: extractOptions{headingLevel: defaultHeadingLevel}}
+	message, found, err := builder.build("v1.3.0", commit)
+	if err != nil || !found {
+		t.Fatalf("build() = %v, %v", found, err)
+	}
+	if err := createTag("v1.3.0", message, tagOptions{commit: commit}); err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	if got := gitCmd(t, "rev-list", "-n", "1", "v1.3.0"); got != rcCommit {
+		t.Errorf("v1.3.0 points at %s, want the rc commit %s", got, rcCommit)
+	}
+	if got := gitCmd(t, "tag", "-l", "--format=%(contents)", "v1.3.0"); got != "- Final release" {
+		t.Errorf("v1.3.0 message = %q, want the v1.3.0 entry", got)
+	}
+
+	gitCmd(t, "tag", "nightly", rcCommit)
+	if commit, _, err := retagTarget("nightly"); err != nil || commit != rcCommit {
+		t.Errorf("retagTarget() of a lightweight tag = %s, %v, want %s", commit, err, rcCommit)
+	}
+	if _, _, err := retagTarget("v9.9.9"); err == nil || !strings.Contains(err.Error(), "cannot retag from 'v9.9.9'") {
+		t.Errorf("retagTarget() of a missing tag error = %v", err)
+	}
+}
+
+// initTestRepo creates a git repository with an initial empty commit on
+// branch "main" in a temporary directory and makes it the working directory
+// for the rest of the test
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "gtauto")
+	t.Setenv("GIT_AUTHOR_EMAIL", "gtauto@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "gtauto")
+	t.Setenv("GIT_COMMITTER_EMAIL", "gtauto@example.com")
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalDir)
+	})
+
+	gitCmd(t, "init", "-q")
+	gitCmd(t, "checkout", "-q", "-b", "main")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "initial")
+	return dir
+}
+
+// gitCmd runs a real git command in the current directory and returns its
+// trimmed output, failing the test on error
+func gitCmd(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestCheckReachable(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "checkout", "-q", "-b", "feature")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "feature work")
+	featureCommit := gitCmd(t, "rev-parse", "--short", "HEAD")
+
+	tests := []struct {
+		name    string
+		commit  string
+		branch  string
+		wantErr string
+	}{
+		{"commit on branch", "main", "feature", ""},
+		{"commit ahead of branch", "feature", "main", "commit " + featureCommit + " is not reachable from branch 'main'"},
+		{"unknown branch", "HEAD", "missing", "cannot check reachability from branch 'missing'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReachable(tt.commit, tt.branch)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkReachable() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkReachable() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDescribeBaseTag(t *testing.T) {
+	initTestRepo(t)
+
+	got, err := describeBaseTag("HEAD")
+	if err != nil || got != "" {
+		t.Errorf("describeBaseTag() without tags = %q, %v, want empty", got, err)
+	}
+
+	gitCmd(t, "tag", "-a", "v1.2.0", "-m", "Release v1.2.0")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "after release")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "more work")
+
+	got, err = describeBaseTag("HEAD")
+	if err != nil {
+		t.Fatalf("describeBaseTag() error = %v", err)
+	}
+	if got != "v1.2.0" {
+		t.Errorf("describeBaseTag() = %q, want %q", got, "v1.2.0")
+	}
+}
+
+func TestCommitterIdentity(t *testing.T) {
+	initTestRepo(t)
+
+	name, email, err := committerIdentity()
+	if err != nil {
+		t.Fatalf("committerIdentity() error = %v", err)
+	}
+	if name != "gtauto" || email != "gtauto@example.com" {
+		t.Errorf("committerIdentity() = %q, %q, want gtauto, gtauto@example.com", name, email)
+	}
+}
+
+func TestRecordRelease(t *testing.T) {
+	var calls []string
+	fakeGit(t, func(stdin string, args []string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		return "", nil
+	})
+
+	when := time.Date(2025, 9, 1, 12, 30, 0, 0, time.UTC)
+	if err := recordRelease("gtauto.lastTag", "gtauto.lastTagDate", "v1.2.0", when); err != nil {
+		t.Fatalf("recordRelease() error = %v", err)
+	}
+	want := []string{
+		"config --local gtauto.lastTag v1.2.0",
+		"config --local gtauto.lastTagDate 2025-09-01T12:30:00Z",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("recordRelease() ran %q, want %q", calls, want)
+	}
+
+	fakeGit(t, func(stdin string, args []string) (string, error) {
+		return "", errors.New("error: invalid key: bad")
+	})
+	if err := recordRelease("bad", "gtauto.lastTagDate", "v1.2.0", when); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("recordRelease() error = %v, want the failing key named", err)
+	}
+}
+
+func TestRecordReleaseOverwrites(t *testing.T) {
+	initTestRepo(t)
+
+	when := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	for _, tag := range []string{"v1.0.0", "v1.1.0"} {
+		if err := recordRelease("gtauto.lastTag", "gtauto.lastTagDate", tag, when); err != nil {
+			t.Fatalf("recordRelease() error = %v", err)
+		}
+	}
+	if got := gitCmd(t, "config", "--local", "--get-all", "gtauto.lastTag"); got != "v1.1.0" {
+		t.Errorf("gtauto.lastTag = %q, want %q", got, "v1.1.0")
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	initTestRepo(t)
+	first := gitCmd(t, "rev-parse", "HEAD")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "second")
+
+	tests := []struct {
+		rev     string
+		want    string
+		wantErr bool
+	}{
+		{first, first, false},
+		{first[:7], first, false},
+		{"HEAD~1", first, false},
+		{"HEAD^{tree}", "", true},
+		{"deadbeef", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveCommit(tt.rev)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveCommit(%q) error = %v, wantErr %v", tt.rev, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveCommit(%q) = %q, want %q", tt.rev, got, tt.want)
+		}
+	}
+}
+
+func TestCommitsSinceLastTag(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "v1.0.0")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "Fix crash")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "Add flag")
+
+	tests := []struct {
+		prevTag string
+		commit  string
+		want    []string
+	}{
+		{"v1.0.0", "HEAD", []string{"Add flag", "Fix crash"}},
+		{"v1.0.0", "HEAD~1", []string{"Fix crash"}},
+		{"v1.0.0", "v1.0.0", nil},
+		{"", "HEAD", []string{"Add flag", "Fix crash", "initial"}},
+	}
+
+	for _, tt := range tests {
+		got, err := commitsSinceLastTag(tt.prevTag, tt.commit)
+		if err != nil {
+			t.Fatalf("commitsSinceLastTag(%q, %q) error = %v", tt.prevTag, tt.commit, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("commitsSinceLastTag(%q, %q) = %q, want %q", tt.prevTag, tt.commit, got, tt.want)
+		}
+	}
+}
+
+func TestIsWorkingTreeClean(t *testing.T) {
+	initTestRepo(t)
+	if err := os.WriteFile("tracked.txt", []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, "add", "tracked.txt")
+	gitCmd(t, "commit", "-q", "-m", "Add tracked.txt")
+
+	clean, dirty, err := isWorkingTreeClean()
+	if err != nil || !clean || len(dirty) != 0 {
+		t.Fatalf("isWorkingTreeClean() of a clean tree = %v, %q, %v", clean, dirty, err)
+	}
+
+	if err := os.WriteFile("tracked.txt", []byte("two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("new.txt", []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	clean, dirty, err = isWorkingTreeClean()
+	if err != nil {
+		t.Fatalf("isWorkingTreeClean() error = %v", err)
+	}
+	if want := []string{"tracked.txt", "new.txt"}; clean || !reflect.DeepEqual(dirty, want) {
+		t.Errorf("isWorkingTreeClean() = %v, %q, want false, %q", clean, dirty, want)
+	}
+}
+
+func TestRunCommandVerbose(t *testing.T) {
+	logFile, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalStderr, originalVerbose := os.Stderr, verbose
+	os.Stderr, verbose = logFile, true
+	t.Cleanup(func() {
+		os.Stderr, verbose = originalStderr, originalVerbose
+	})
+
+	if _, _, err := runCommand(exec.Command("git", "version"), ""); err != nil {
+		t.Fatalf("runCommand(git version) error = %v", err)
+	}
+	if _, _, err := runCommand(exec.Command("git", "rev-parse", "--verify", "no such ref"), ""); err == nil {
+		t.Fatal("runCommand() of a failing command returned nil error")
+	}
+	verbose = false
+	if _, _, err := runCommand(exec.Command("git", "version"), ""); err != nil {
+		t.Fatalf("runCommand(git version) error = %v", err)
+	}
+
+	data, err := os.ReadFile(logFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	if got := strings.Count(log, "+ git version\n"); got != 1 {
+		t.Errorf("log has %d 'git version' lines, want 1 (only while verbose):\n%s", got, log)
+	}
+	if !strings.Contains(log, "+ git rev-parse --verify 'no such ref'\n") || !strings.Contains(log, "  stderr: ") {
+		t.Errorf("log does not show the failed command and its stderr:\n%s", log)
+	}
+}
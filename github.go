@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// githubOutputDelimiter returns a heredoc delimiter for a multi-line
+// GitHub Actions output value that does not occur as a line of value
+func githubOutputDelimiter(value string) string {
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(value, "\n") {
+		lines[line] = true
+	}
+	delimiter := "EOF"
+	for n := 1; lines[delimiter]; n++ {
+		delimiter = fmt.Sprintf("EOF_%d", n)
+	}
+	return delimiter
+}
+
+// writeGitHubOutput appends the tag, whether it was created and its notes
+// to the GitHub Actions output file at path
+func writeGitHubOutput(path, tagName string, created bool, notes string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	delimiter := githubOutputDelimiter(notes)
+	_, err = fmt.Fprintf(file, "tag=%s\ncreated=%s\nnotes<<%s\n%s\n%s\n",
+		tagName, strconv.FormatBool(created), delimiter, notes, delimiter)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// reportGitHubOutput writes the result to $GITHUB_OUTPUT for --github-output,
+// warning instead of failing when it cannot
+func reportGitHubOutput(tagName string, created bool, notes string) {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		printWarning("$GITHUB_OUTPUT is not set, skipping GitHub Actions output")
+		return
+	}
+	if err := writeGitHubOutput(path, tagName, created, notes); err != nil {
+		printWarning(fmt.Sprintf("Failed to write GitHub Actions output: %v", err))
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitHubOutputDelimiter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"- Added feature", "EOF"},
+		{"Usage:\ncat <<EOF\nhello\nEOF\n", "EOF_1"},
+		{"EOF\nEOF_1", "EOF_2"},
+	}
+
+	for _, tt := range tests {
+		if got := githubOutputDelimiter(tt.value); got != tt.want {
+			t.Errorf("githubOutputDelimiter(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteGitHubOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(path, []byte("previous=step\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create output file: %v", err)
+	}
+
+	if err := writeGitHubOutput(path, "v1.2.0", true, "### Added\n- New feature"); err != nil {
+		t.Fatalf("writeGitHubOutput() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	want := "previous=step\ntag=v1.2.0\ncreated=true\nnotes<<EOF\n### Added\n- New feature\nEOF\n"
+	if string(got) != want {
+		t.Errorf("output file = %q, want %q", got, want)
+	}
+}
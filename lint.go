@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// lintIssue is a problem found by --lint-changelog
+type lintIssue struct {
+	// Line is the 1-based line number the problem was found on
+	Line    int
+	Message string
+}
+
+// sectionDateLayout is the date format expected after a version header
+const sectionDateLayout = "2006-01-02"
+
+// lintSectionDates reports section dates that are not real calendar dates
+// and, unless allowFuture is set, dates later than today
+func lintSectionDates(sections []changelogSection, today time.Time, allowFuture bool) []lintIssue {
+	var issues []lintIssue
+	for _, section := range sections {
+		if section.Date == "" {
+			continue
+		}
+		date, err := time.Parse(sectionDateLayout, section.Date)
+		if err != nil {
+			issues = append(issues, lintIssue{section.Line, fmt.Sprintf("invalid date '%s' for %s", section.Date, section.Version)})
+			continue
+		}
+		if !allowFuture && date.Format(sectionDateLayout) > today.Format(sectionDateLayout) {
+			issues = append(issues, lintIssue{section.Line, fmt.Sprintf("future date '%s' for %s", section.Date, section.Version)})
+		}
+	}
+	return issues
+}
+
+// lintChangelog checks changelogFile and returns the problems found
+func lintChangelog(changelogFile string, opts extractOptions, allowFuture bool) ([]lintIssue, error) {
+	sections, err := parseChangelogSections(changelogFile, opts)
+	if err != nil {
+		return nil, err
+	}
+	return lintSectionDates(sections, time.Now(), allowFuture), nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLintSectionDates(t *testing.T) {
+	today := time.Date(2025, 8, 27, 0, 0, 0, 0, time.UTC)
+	sections := []changelogSection{
+		{Version: "v1.3.0", Date: "2025-09-01", Line: 3},
+		{Version: "v1.2.0", Date: "2025-13-40", Line: 7},
+		{Version: "v1.1.0", Date: "2025-02-29", Line: 11},
+		{Version: "v1.0.1", Date: "2025-08-27", Line: 15},
+		{Version: "v1.0.0", Line: 19},
+	}
+
+	tests := []struct {
+		name        string
+		allowFuture bool
+		want        []lintIssue
+	}{
+		{
+			name: "future dates rejected",
+			want: []lintIssue{
+				{3, "future date '2025-09-01' for v1.3.0"},
+				{7, "invalid date '2025-13-40' for v1.2.0"},
+				{11, "invalid date '2025-02-29' for v1.1.0"},
+			},
+		},
+		{
+			name:        "future dates allowed",
+			allowFuture: true,
+			want: []lintIssue{
+				{7, "invalid date '2025-13-40' for v1.2.0"},
+				{11, "invalid date '2025-02-29' for v1.1.0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintSectionDates(sections, today, tt.allowFuture)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lintSectionDates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
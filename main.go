@@ -3,16 +3,48 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"text/template"
+	"time"
 )
 
 var version = "1.0.0" // Set during build
 
+// defaultHeadingLevel is the markdown heading level of version headers (##)
+const defaultHeadingLevel = 2
+
+// defaultMaxLines is how many CHANGELOG lines extraction scans before it
+// gives up on the file as malformed
+const defaultMaxLines = 1000000
+
+// defaultSubjectMax is the longest tag message subject line before
+// --subject-max shortens it, following the git convention of 50 characters
+const defaultSubjectMax = 50
+
+// out receives the progress messages and tag preview; warnings and errors
+// always go to stderr. --print-after moves out to stderr as well so that
+// stdout carries only the final tag message.
+var out io.Writer = os.Stdout
+
+// quiet is set with --quiet: only errors are printed
+var quiet bool
+
+// Prefixes of the messages printed by printError, printWarning and
+// printSuccess, set with --error-prefix, --warning-prefix and
+// --success-prefix
+var (
+	errorPrefix   = "Error: "
+	warningPrefix = "Warning: "
+	successPrefix = ""
+)
+
 const (
 	colorRed    = "\033[0;31m"
 	colorGreen  = "\033[0;32m"
@@ -20,134 +52,1824 @@ const (
 	colorReset  = "\033[0m"
 )
 
-func main() {
-	tagName := flag.String("tag", "", "Tag name to create (required)")
-	changelogFile := flag.String("changelog", "CHANGELOG.md", "Path to CHANGELOG file")
-	showHelp := flag.Bool("h", false, "Show help message")
-	showHelpLong := flag.Bool("help", false, "Show help message")
-	showVersion := flag.Bool("version", false, "Show version information")
-	force := flag.Bool("force", false, "Force overwrite existing tag without confirmation")
+// colorEnabled reports whether messages are colored. main sets it once at
+// startup with shouldColor.
+var colorEnabled = true
+
+// colorize wraps msg in the ANSI color code when colors are enabled
+func colorize(code, msg string) string {
+	if !colorEnabled {
+		return msg
+	}
+	return code + msg + colorReset
+}
+
+// shouldColor reports whether messages written to w should be colored:
+// not if the NO_COLOR environment variable is set, to any value, and only
+// if w is a terminal
+func shouldColor(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// flagRule declares how the flag name combines with the others. Rules are
+// checked by checkFlagRules against the flags given on the command line; one
+// flag may have several rules, e.g. with different reasons.
+type flagRule struct {
+	name string
+	// git marks a flag that needs a git repository, so it cannot be used
+	// with --no-git
+	git bool
+	// conflicts are the flags that cannot be used together with name
+	conflicts []string
+	// requires are flags of which at least one must be given with name
+	requires []string
+	// reason is added to the error, if set
+	reason string
+}
+
+// flagRules are the rules of the flags defined in main, in the order they
+// are checked
+var flagRules = []flagRule{
+	{name: "quiet", conflicts: []string{"verbose", "json"}},
+	{name: "json", conflicts: []string{"batch", "backfill-tags", "lint-changelog", "reformat", "print-after"}},
+	{name: "heading-level", conflicts: []string{"heading-offset"}},
+
+	// Naming the tag
+	{name: "tag-from-branch", git: true, conflicts: []string{"tag"}},
+	{name: "bump-prerelease", git: true, conflicts: []string{"tag", "tag-from-branch"}},
+	{name: "bump", git: true, conflicts: []string{"tag", "tag-from-branch", "bump-prerelease"}},
+	{name: "calver", git: true, conflicts: []string{"tag", "tag-from-branch", "bump", "bump-prerelease"}},
+
+	// Batch and backfill runs
+	{name: "batch", git: true, conflicts: []string{"tag", "tag-from-branch", "bump", "bump-prerelease", "calver", "lightweight", "dry-run", "push", "push-follow", "record-config", "verify-command", "from-describe", "webhook"}},
+	{name: "backfill-tags", git: true, conflicts: []string{"tag", "tag-from-branch", "bump", "bump-prerelease", "calver", "batch", "push", "push-follow", "record-config", "verify-command", "lightweight", "retag-from", "from-describe", "webhook"}},
+	{name: "commit-map", git: true, requires: []string{"backfill-tags"}},
+	{name: "notes-dir", requires: []string{"batch", "backfill-tags"}},
+	{name: "resume", requires: []string{"batch"}},
+	{name: "batch-delay", requires: []string{"batch", "backfill-tags"}},
+	{name: "restart", requires: []string{"resume"}},
+
+	// Listing and reporting
+	{name: "audit", git: true},
+	{name: "list-tags", git: true},
+
+	// The commit to tag
+	{name: "commit", git: true, conflicts: []string{"retag-from"}},
+	{name: "commit", conflicts: []string{"batch", "backfill-tags"}, reason: "list the commits in the file instead"},
+	{name: "retag-from", git: true, conflicts: []string{"batch"}, reason: "list the commits in the batch file instead"},
+	{name: "from-describe", git: true},
+	{name: "require-reachable-from", git: true},
+	{name: "no-overwrite", git: true},
+	{name: "skip-if-unchanged", git: true},
+
+	// The tag message
+	{name: "template", git: true},
+	{name: "annotate-from-file", git: true, conflicts: []string{"template"}},
+	{name: "require-changelog-placeholder", requires: []string{"annotate-from-file", "template"}},
+	{name: "footer-template", git: true},
+	{name: "append-diffstat", git: true},
+	{name: "normalize-trailers", git: true},
+	{name: "message", conflicts: []string{"message-file", "lightweight", "batch", "backfill-tags", "from-unreleased", "from-describe"}},
+	{name: "message-file", conflicts: []string{"lightweight", "batch", "backfill-tags", "from-unreleased", "from-describe"}},
+	{name: "message", conflicts: []string{"min-bullets", "forbid-markers", "expect-checksum", "allowed-sections", "strip-heading"}, reason: "the given message is not a CHANGELOG entry to check"},
+	{name: "message-file", conflicts: []string{"min-bullets", "forbid-markers", "expect-checksum", "allowed-sections", "strip-heading"}, reason: "the given message is not a CHANGELOG entry to check"},
+	{name: "from-git-log", git: true},
+	{name: "conventional", git: true, conflicts: []string{"from-git-log", "lightweight", "message", "message-file"}},
+	{name: "require-changelog", conflicts: []string{"lightweight", "message", "message-file", "from-git-log", "conventional"}},
+	{name: "from-unreleased", conflicts: []string{"batch", "backfill-tags", "from-describe"}},
+	{name: "update-changelog", git: true, requires: []string{"from-unreleased"}},
+	{name: "date", requires: []string{"from-unreleased", "conventional"}},
+	{name: "reset-unreleased", git: true},
+	// Everything that shapes or checks the message
+	{name: "lightweight", conflicts: []string{
+		"output", "append-message", "template", "annotate-from-file", "footer-template", "signoff", "stamp-tool-version",
+		"append-diffstat", "normalize-trailers", "subject-max", "strip-heading", "from-unreleased", "from-git-log",
+		"min-bullets", "forbid-markers", "expect-checksum", "allowed-sections", "warn-unknown-sections",
+		"skip-if-unchanged", "clipboard",
+	}, reason: "a lightweight tag has no message"},
+
+	// After tagging
+	{name: "github-output", git: true},
+	{name: "print-after", git: true},
+	{name: "verify-command", git: true},
+	{name: "record-config", git: true},
+	{name: "push", git: true, conflicts: []string{"push-follow"}},
+	// --follow-tags skips lightweight tags
+	{name: "push-follow", git: true, conflicts: []string{"lightweight"}, reason: "git push --follow-tags skips lightweight tags; use --push instead"},
+	// Only a CHANGELOG entry can be found, not a given message
+	{name: "require-changelog-for-push", git: true, conflicts: []string{"message", "message-file", "lightweight"}},
+	{name: "require-changelog-for-push", requires: []string{"push", "push-follow"}},
+	{name: "webhook", git: true},
+	{name: "webhook-template", git: true, requires: []string{"webhook"}},
+	{name: "webhook-required", git: true, requires: []string{"webhook"}},
+	{name: "require-up-to-date", git: true},
+	{name: "require-clean", git: true},
+
+	// Signing
+	{name: "sign", git: true},
+	{name: "local-user", git: true},
+	{name: "sign-format", git: true},
+	{name: "ssh-key", git: true, conflicts: []string{"local-user"}, requires: []string{"sign-format"}},
+	{name: "lightweight", conflicts: []string{"sign", "local-user", "sign-format", "ssh-key"}, reason: "signed tags are always annotated"},
+}
+
+func main() {
+	tagName := flag.String("tag", "", "Tag name to create (required)")
+	changelogs := &changelogFlag{files: []string{"CHANGELOG.md"}}
+	flag.Var(changelogs, "changelog", "Path to CHANGELOG file; repeat it to also look for the entry in more files")
+	var tagMessage string
+	flag.StringVar(&tagMessage, "message", "", "Use this text verbatim as the tag message instead of the CHANGELOG entry")
+	flag.StringVar(&tagMessage, "m", "", "Alias for --message")
+	var appendMessages stringList
+	flag.Var(&appendMessages, "append-message", "Add this text as a paragraph after the CHANGELOG entry or --message; may be repeated")
+	messageFile := flag.String("message-file", "", "Read the tag message from this file ('-' for stdin) instead of the CHANGELOG entry")
+	changelogCandidates := flag.String("changelog-candidates", defaultChangelogCandidates, "Comma-separated file names looked up case-insensitively, relative to the repository root, when --changelog is not set and CHANGELOG.md does not exist")
+	showHelp := flag.Bool("h", false, "Show help message")
+	showHelpLong := flag.Bool("help", false, "Show help message")
+	showVersion := flag.Bool("version", false, "Show version information")
+	force := flag.Bool("force", false, "Force overwrite existing tag without confirmation")
+	noValidate := flag.Bool("no-validate", false, "Allow tag names that are not semantic versions, e.g. for other versioning schemes")
+	jsonOut := flag.Bool("json", false, "Print a single JSON object with the result, or the error, to stdout instead of progress messages")
+	dryRun := flag.Bool("dry-run", false, "Show the tag message and the git commands without changing the repository; with --format json, print them as a JSON plan")
+	fromBranch := flag.Bool("tag-from-branch", false, "Derive the tag name from the current branch (e.g. release/v1.2.0)")
+	branchPrefix := flag.String("branch-prefix", "release/", "Branch name prefix stripped by --tag-from-branch")
+	tagPrefix := flag.String("prefix", "", "Component prefix of the tag (e.g. frontend- for frontend-v1.2.0), left out of CHANGELOG headers")
+	bumpPart := flag.String("bump", "", "Derive the tag by bumping the latest semver tag: major, minor or patch")
+	bumpPrereleaseLabel := flag.String("bump-prerelease", "", "Derive the tag by bumping the pre-release of the latest tag with this label (e.g. rc)")
+	calver := flag.Bool("calver", false, "Derive the tag from today's date (e.g. 2025.08.27), adding .1, .2, ... if today is already tagged")
+	profile := flag.String("profile", "", "Named profile from .gtauto.yml to apply")
+	normalize := flag.Bool("normalize-trailers", false, "Normalize and de-duplicate message trailers via git interpret-trailers")
+	audit := flag.Bool("audit", false, "Report which changelog versions are tagged instead of creating a tag")
+	format := flag.String("format", "text", "Output format for --audit (text, json or csv), --list-tags, --compare and --dry-run (text or json); frontmatter prints the CHANGELOG entry with YAML front matter instead of creating a tag")
+	reformat := flag.Bool("reformat", false, "Print the CHANGELOG entry in strict Keep a Changelog form instead of creating a tag")
+	output := flag.String("output", "", "Also write the tag message to this file ('-' for stdout), with or without --dry-run; for --audit, --list-tags, --compare, --reformat or --no-git, write their output there instead of stdout")
+	fromUnreleased := flag.Bool("from-unreleased", false, "Use the [Unreleased] section as the tag message when the CHANGELOG has no entry for the tag")
+	stripHeadingFlag := flag.Bool("strip-heading", false, "Remove the version header line from the CHANGELOG entry, keeping only its content")
+	fromGitLog := flag.Bool("from-git-log", false, "List the commit subjects since the previous tag in the tag message when the CHANGELOG has no entry for the tag")
+	conventional := flag.Bool("conventional", false, "Build the tag message from the Conventional Commits since the previous tag, grouped by type, when the CHANGELOG has no entry for the tag")
+	requireChangelog := flag.Bool("require-changelog", false, "Fail with exit status 4 instead of tagging with a generic message when the CHANGELOG has no entry for the tag")
+	updateChangelog := flag.Bool("update-changelog", false, "With --from-unreleased, rename [Unreleased] to the new version and --date after tagging")
+	dateFlag := flag.String("date", "today", "With --from-unreleased, the release date of the new version header, YYYY-MM-DD or today (local time)")
+	resetUnreleasedFlag := flag.Bool("reset-unreleased", false, "Add an empty [Unreleased] section to the CHANGELOG after tagging")
+	unreleasedSections := flag.String("unreleased-sections", defaultUnreleasedSections, "Comma-separated subsections stubbed out by --reset-unreleased")
+	verifyCommand := flag.String("verify-command", "", "Release gate: shell command that must succeed before tagging (exit status 3 if it fails)")
+	requireClean := flag.Bool("require-clean", false, "Refuse to tag if the working tree has uncommitted changes or untracked files")
+	requireUpToDate := flag.Bool("require-up-to-date", false, "Fetch the upstream branch and refuse to tag if HEAD is behind it")
+	reachableFrom := flag.String("require-reachable-from", "", "Refuse to tag unless the commit is reachable from this branch")
+	usePager := flag.Bool("pager", false, "Show the tag message preview through $PAGER (default: less -R) in a terminal")
+	templateFile := flag.String("template", "", "Render the tag message from a Go text/template file")
+	annotateFile := flag.String("annotate-from-file", "", "Render the tag message from a file where {{.Changelog}} marks the CHANGELOG entry")
+	requirePlaceholder := flag.Bool("require-changelog-placeholder", false, "Fail unless the --annotate-from-file or --template file uses {{.Changelog}}")
+	footerFile := flag.String("footer-template", "", "Append a footer rendered from a Go text/template file to the tag message")
+	templateDelimsFlag := flag.String("template-delims", "", "Action delimiters for --template, --annotate-from-file and --footer-template, e.g. \"<< >>\"")
+	appendDiffstat := flag.Bool("append-diffstat", false, "Append a summary of the changes since the previous semver tag to the tag message")
+	forbidMarkers := flag.String("forbid-markers", "", "Comma-separated markers (e.g. TODO,FIXME) that must not appear in the CHANGELOG entry")
+	allowedSections := flag.String("allowed-sections", "", "Comma-separated subsections (e.g. Added,Changed,Fixed) the CHANGELOG entry may use; others are an error")
+	warnUnknownSections := flag.Bool("warn-unknown-sections", false, "Warn about subsections outside --allowed-sections (default: the Keep a Changelog ones) instead of failing")
+	commitFlag := flag.String("commit", "", "Tag this commit (SHA, branch or other revision) instead of HEAD")
+	retagFrom := flag.String("retag-from", "", "Create the tag at the commit of this existing tag, e.g. to promote an rc")
+	fromDescribe := flag.Bool("from-describe", false, "Use the CHANGELOG entry of the nearest tag found by git describe")
+	forceMove := flag.Bool("force-move", false, "Allow recreating an existing tag at a different commit")
+	noOverwrite := flag.Bool("no-overwrite", false, "Fail if the tag already exists, even with --force")
+	minBullets := flag.Int("min-bullets", 0, "Fail unless the CHANGELOG entry has at least this many bullet points")
+	expectChecksum := flag.String("expect-checksum", "", "Fail unless the SHA-256 of the CHANGELOG entry matches this value")
+	skipIfUnchanged := flag.Bool("skip-if-unchanged", false, "Do nothing if the existing tag already has the same message")
+	listTagsFlag := flag.Bool("list-tags", false, "List all tags, highest version first, with their CHANGELOG and signature status")
+	filter := flag.String("filter", "", "With --list-tags, only list tags matching this glob, e.g. 'v1.*'")
+	compare := flag.String("compare", "", "Diff the CHANGELOG entries of two versions: --compare <tagA>..<tagB> or --compare <tagA> <tagB>")
+	lint := flag.Bool("lint-changelog", false, "Check the CHANGELOG for problems such as invalid section dates instead of creating a tag")
+	allowFutureDates := flag.Bool("allow-future-dates", false, "With --lint-changelog, accept section dates later than today")
+	stampToolVersion := flag.Bool("stamp-tool-version", false, "Append a trailer with the gtauto version to the tag message")
+	stampKey := flag.String("stamp-key", "Generated-by", "Trailer key used by --stamp-tool-version")
+	signoff := flag.Bool("signoff", false, "Append a Signed-off-by trailer for the tagger to the tag message")
+	sign := flag.Bool("sign", false, "Create a GPG-signed tag (git tag -s)")
+	lightweight := flag.Bool("lightweight", false, "Create a lightweight tag without a message; the CHANGELOG is not read")
+	var localUser string
+	flag.StringVar(&localUser, "local-user", "", "Sign the tag with this GPG key ID (implies --sign)")
+	flag.StringVar(&localUser, "signing-key", "", "Alias for --local-user")
+	signFormat := flag.String("sign-format", "", "Signature format for the tag: openpgp, x509 or ssh (sets gpg.format; implies --sign)")
+	sshKey := flag.String("ssh-key", "", "With --sign-format ssh, sign with this SSH key file (sets user.signingkey)")
+	taggerName := flag.String("tagger-name", "", "Tagger name for the tag and --signoff (default: from git config)")
+	taggerEmail := flag.String("tagger-email", "", "Tagger email for the tag and --signoff (default: from git config)")
+	clipboard := flag.Bool("clipboard", false, "Copy the tag message to the system clipboard after tagging")
+	githubOutput := flag.Bool("github-output", false, "Append tag, created and notes outputs to $GITHUB_OUTPUT for GitHub Actions")
+	printAfter := flag.Bool("print-after", false, "Print the final tag message to stdout after tagging; progress output goes to stderr")
+	push := flag.Bool("push", false, "Push the tag to --remote after creating it")
+	pushFollow := flag.Bool("push-follow", false, "Push the current branch with the tag after creating it (git push --follow-tags)")
+	remote := flag.String("remote", "origin", "Remote used by --push and --push-follow")
+	requireChangelogForPush := flag.Bool("require-changelog-for-push", false, "With --push or --push-follow, push only if a CHANGELOG entry was found; with the fallback message the tag stays local")
+	webhookURL := flag.String("webhook", "", "POST a JSON notification (tag, message, repo, commit) to this URL after tagging")
+	webhookTemplate := flag.String("webhook-template", "", "Render the --webhook request body from a Go text/template file, e.g. for Slack's {\"text\": ...}")
+	webhookRequired := flag.Bool("webhook-required", false, "Exit with an error if the --webhook notification fails (default: warn only)")
+	recordConfig := flag.Bool("record-config", false, "Record the tag and its creation time in the local git config after tagging")
+	recordTagKey := flag.String("record-tag-key", "gtauto.lastTag", "Git config key used by --record-config for the tag")
+	recordDateKey := flag.String("record-date-key", "gtauto.lastTagDate", "Git config key used by --record-config for the date")
+	batchFile := flag.String("batch", "", "Create every tag listed in a file (one '<tag> [<commit>]' per line)")
+	backfill := flag.Bool("backfill-tags", false, "Create the missing tags for CHANGELOG versions listed in --commit-map, after confirmation")
+	commitMap := flag.String("commit-map", "", "With --backfill-tags, a file of '<version> <commit>' lines giving the commit of each version")
+	notesDir := flag.String("notes-dir", "", "With --batch, also write each tag message to <dir>/<tag>.md")
+	resume := flag.String("resume", "", "With --batch, record handled tags in this state file and skip them when run again")
+	restart := flag.Bool("restart", false, "With --resume, ignore and replace the existing state file")
+	batchDelay := flag.Duration("batch-delay", 0, "With --batch or --backfill-tags, wait this long between created tags, e.g. 2s, to spare signing hardware or hooks")
+	noGit := flag.Bool("no-git", false, "Only extract the CHANGELOG entry for --tag to stdout or --output, without git")
+	timing := flag.Bool("timing", false, "Print how long each phase took and the total at the end")
+	subjectMax := flag.Int("subject-max", defaultSubjectMax, "Shorten a longer first line of the tag message with an ellipsis, keeping the full line in the body; 0 disables")
+	maxLines := flag.Int("max-lines", defaultMaxLines, "Fail if extracting the CHANGELOG entry needs to scan more than this many lines")
+	headingLevelFlag := flag.Int("heading-level", defaultHeadingLevel, "Markdown heading level of version headers (1-6, e.g. 3 for ###), or 0 to detect it from the CHANGELOG")
+	headingOffset := flag.Int("heading-offset", 0, "Shift the version heading level relative to ## (e.g. 1 for ###)")
+	changelogFormatFlag := flag.String("changelog-format", "", "CHANGELOG format, markdown or rst (default: rst for a .rst file, markdown otherwise)")
+	headerPatternFlag := flag.String("header-pattern", "", "Go regexp matching CHANGELOG version headers, capturing the version in (?P<version>...), instead of the built-in ones")
+	flag.BoolVar(&verbose, "verbose", false, "Log each git command to stderr, with its output when it fails")
+	flag.BoolVar(&quiet, "quiet", false, "Print nothing but errors, which go to stderr; the exit status tells the outcome")
+	flag.StringVar(&errorPrefix, "error-prefix", errorPrefix, "Prefix of error messages")
+	flag.StringVar(&warningPrefix, "warning-prefix", warningPrefix, "Prefix of warning messages")
+	flag.StringVar(&successPrefix, "success-prefix", successPrefix, "Prefix of success messages")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gtauto: Git tag automation with CHANGELOG support\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag <tag_name> [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag-from-branch [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --bump major|minor|patch [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --bump-prerelease <label> [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --calver [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --batch <file> [--notes-dir <dir>] [--resume <state-file>] [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --backfill-tags --commit-map <file> [--force] [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --audit [--format text|json|csv] [--output <file>]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --list-tags [--filter <glob>] [--format text|json|checklist]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto list [--filter <glob>] [--format text|json|checklist]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto delete --tag <tag_name> [--remote <name>] [--force] [--dry-run]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto retag-all --force|--dry-run [options]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag <tag_name> --lightweight\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --lint-changelog [--allow-future-dates]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --compare <tagA>..<tagB> [--format text|json]\n")
+		fmt.Fprintf(os.Stderr, "  gtauto [--format text|json] --compare <tagA> <tagB>\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nLightweight tags:\n")
+		fmt.Fprintf(os.Stderr, "  --lightweight runs 'git tag <tag_name>' without -a/-m. No CHANGELOG entry is\n")
+		fmt.Fprintf(os.Stderr, "  extracted because lightweight tags carry no message. It cannot be combined\n")
+		fmt.Fprintf(os.Stderr, "  with --sign or --local-user, nor with the options that shape or check the\n")
+		fmt.Fprintf(os.Stderr, "  message, such as --template, --signoff or --min-bullets.\n")
+		fmt.Fprintf(os.Stderr, "\nPushing:\n")
+		fmt.Fprintf(os.Stderr, "  --push runs 'git push <remote> refs/tags/<tag_name>', pushing only the new tag.\n")
+		fmt.Fprintf(os.Stderr, "  --push-follow runs 'git push --follow-tags <remote>' instead, pushing the\n")
+		fmt.Fprintf(os.Stderr, "  current branch to its upstream together with the tag. The branch must have an\n")
+		fmt.Fprintf(os.Stderr, "  upstream, and the tagged commit must be on it for the tag to be pushed.\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --changelog path/to/CHANGELOG.md\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag frontend-v1.2.0 --prefix frontend- --changelog frontend/CHANGELOG.md\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --force\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --dry-run\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.1 -m \"Hotfix: fix crash on startup\"\n")
+		fmt.Fprintf(os.Stderr, "  generate-notes | gtauto --tag v1.2.0 --message-file -\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.2.0 --append-message \"Docs: https://example.com/docs\"\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --json\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag nightly --lightweight --no-validate\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --push --remote upstream\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --push-follow\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --push --require-changelog-for-push\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --push --webhook https://hooks.example.com/release\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --force --skip-if-unchanged\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0-beta.1 --profile beta\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag-from-branch --branch-prefix release/\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --bump patch\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --bump-prerelease rc\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --calver --prefix app-\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.3.0-rc.1 --from-describe\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --commit abc123\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.2.0 --conventional\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.3.0 --retag-from v1.3.0-rc.2\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.3.0 --verify-command 'make test'\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --batch releases.txt --notes-dir notes\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --batch releases.txt --resume releases.state\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --batch releases.txt --batch-delay 2s\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --audit --format csv --output releases.csv\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --reformat --output notes.md\n")
+		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --no-git --output notes.md\n")
+	}
+
+	command, args := splitSubcommand(os.Args[1:])
+	_ = flag.CommandLine.Parse(args)
+	// The flags given on the command line, before any config defaults
+	given := givenFlags(flag.CommandLine)
+
+	// The list subcommand is --list-tags with a checklist by default
+	if command == "list" {
+		*listTagsFlag = true
+		if !given["format"] && !*jsonOut {
+			*format = "checklist"
+		}
+	}
+
+	var timer *phaseTimer
+	if *timing {
+		timer = newPhaseTimer(time.Now)
+	}
+
+	if *showHelp || *showHelpLong {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	if *showVersion {
+		fmt.Printf("gtauto version %s\n", version)
+		os.Exit(0)
+	}
+
+	// Keep stdout for the tag message
+	if *printAfter || *noGit || (*dryRun && *format == "json") || *output == "-" {
+		out = os.Stderr
+	}
+	colorEnabled = shouldColor(out) && shouldColor(os.Stderr)
+
+	// With --quiet only errors are printed
+	if quiet {
+		out = io.Discard
+		report = quietReporter{}
+		colorEnabled = shouldColor(os.Stderr)
+	}
+
+	// With --json the only output is one JSON object on stdout
+	if *jsonOut {
+		out = io.Discard
+		report = &jsonReporter{w: os.Stdout}
+		if *format != "text" && *format != "json" {
+			printError(fmt.Sprintf("--json cannot be used with --format %s", *format))
+			os.Exit(1)
+		}
+		*format = "json"
+	}
+
+	if err := checkFlagRules(flagRules, given); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if *signFormat != "" && !contains(signFormats, *signFormat) {
+		printError(fmt.Sprintf("Invalid --sign-format %q: use openpgp, x509 or ssh", *signFormat))
+		os.Exit(1)
+	}
+	if *sshKey != "" {
+		if *signFormat != "ssh" {
+			printError("--ssh-key requires --sign-format ssh")
+			os.Exit(1)
+		}
+		warning, err := checkSSHKey(*sshKey)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if warning != "" {
+			printWarning(warning)
+		}
+	}
+	// Scripts may pass a fully-qualified ref; match and create the short name
+	*tagName = shortTagName(*tagName)
+
+	if *messageFile != "" {
+		message, err := readMessageFile(*messageFile, os.Stdin)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to read the tag message: %v", err))
+			os.Exit(1)
+		}
+		tagMessage = message
+	}
+
+	// Audit, listing, lint, compare, batch and backfill runs don't create a
+	// single named tag
+	needsTag := !*audit && !*listTagsFlag && !*lint && *compare == "" && *batchFile == "" && !*backfill && command != "retag-all"
+
+	if *tagName == "" && !*fromBranch && *bumpPrereleaseLabel == "" && *bumpPart == "" && !*calver && needsTag {
+		printError("--tag option is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Zero selects detection once the CHANGELOG is known
+	headingLevel := *headingLevelFlag
+	if given["heading-offset"] {
+		headingLevel = defaultHeadingLevel + *headingOffset
+		if headingLevel < 1 || headingLevel > 6 {
+			printError(fmt.Sprintf("--heading-offset %d gives heading level %d, must be between 1 and 6", *headingOffset, headingLevel))
+			os.Exit(1)
+		}
+	} else if headingLevel < 0 || headingLevel > 6 {
+		printError(fmt.Sprintf("--heading-level must be between 1 and 6, or 0 to detect it, got %d", headingLevel))
+		os.Exit(1)
+	}
+
+	if *subjectMax < 0 {
+		printError(fmt.Sprintf("--subject-max must not be negative, got %d", *subjectMax))
+		os.Exit(1)
+	}
+
+	if *batchDelay < 0 {
+		printError(fmt.Sprintf("--batch-delay must not be negative, got %s", *batchDelay))
+		os.Exit(1)
+	}
+
+	if *maxLines < 1 {
+		printError(fmt.Sprintf("--max-lines must be at least 1, got %d", *maxLines))
+		os.Exit(1)
+	}
+
+	// Check if we're in a git repository
+	if !*noGit {
+		if err := checkGitRepository(); err != nil {
+			printError(fmt.Sprintf("Not a git repository: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if command == "delete" {
+		if *noGit {
+			printError("delete cannot be used with --no-git")
+			os.Exit(1)
+		}
+		// Only a --remote given on the command line deletes the remote tag,
+		// never a config default
+		deleteRemote := ""
+		if given["remote"] {
+			deleteRemote = *remote
+		}
+		if err := runDelete(*tagName, deleteRemote, *force, *dryRun); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *fromBranch {
+		branch, err := currentBranch()
+		if err != nil {
+			printError(fmt.Sprintf("Cannot determine current branch: %v", err))
+			os.Exit(1)
+		}
+		*tagName, err = tagFromBranch(branch, *branchPrefix)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		printSuccess(fmt.Sprintf("Using tag '%s' from branch '%s'", *tagName, branch))
+	}
+
+	if *bumpPrereleaseLabel != "" {
+		latest, err := latestSemverTag()
+		if err != nil {
+			printError(fmt.Sprintf("Cannot determine the latest tag: %v", err))
+			os.Exit(1)
+		}
+		*tagName, err = bumpPrerelease(latest, *bumpPrereleaseLabel)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if latest == "" {
+			printSuccess(fmt.Sprintf("Using tag '%s' (no previous semver tag)", *tagName))
+		} else {
+			printSuccess(fmt.Sprintf("Using tag '%s' after '%s'", *tagName, latest))
+		}
+	}
+
+	if *bumpPart != "" {
+		latest, err := latestSemverTag()
+		if err != nil {
+			printError(fmt.Sprintf("Cannot determine the latest tag: %v", err))
+			os.Exit(1)
+		}
+		*tagName, err = bumpVersion(latest, *bumpPart)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if latest == "" {
+			printSuccess(fmt.Sprintf("Using tag '%s' (no previous semver tag)", *tagName))
+		} else {
+			printSuccess(fmt.Sprintf("Using tag '%s' after '%s'", *tagName, latest))
+		}
+	}
+
+	if *calver {
+		existing, err := listAllTags()
+		if err != nil {
+			printError(fmt.Sprintf("Cannot list tags: %v", err))
+			os.Exit(1)
+		}
+		*tagName = nextCalverTag(existing, *tagPrefix, time.Now())
+		printSuccess(fmt.Sprintf("Using tag '%s'", *tagName))
+	}
+
+	if needsTag {
+		if err := checkTagName(*tagName); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if !strings.HasPrefix(*tagName, *tagPrefix) {
+			printError(fmt.Sprintf("Tag '%s' does not start with --prefix '%s'", *tagName, *tagPrefix))
+			os.Exit(1)
+		}
+		// Only tags need to be semantic versions; extracting an entry does not
+		if !*noValidate && !*calver && !*noGit && !*reformat && *format != "frontmatter" {
+			if err := validateSemver(strings.TrimPrefix(*tagName, *tagPrefix)); err != nil {
+				printError(err.Error() + " (use --no-validate to allow it)")
+				os.Exit(1)
+			}
+		}
+		if *noOverwrite {
+			if err := checkNoOverwrite(*tagName); err != nil {
+				printError(err.Error())
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Apply defaults from the user and repository configs; command-line
+	// flags take precedence
+	var root string
+	var err error
+	if *noGit {
+		root, err = os.Getwd()
+	} else {
+		root, err = gitRoot()
+	}
+	if err != nil {
+		printError(fmt.Sprintf("Failed to load config: %v", err))
+		os.Exit(1)
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		printError(fmt.Sprintf("Failed to load config: %v", err))
+		os.Exit(1)
+	}
+	values, err := cfg.resolve(*profile)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if err := applyConfig(values); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	// As with git tag -u, a key or signature format implies signing. The
+	// flag rules only saw the command line, so check a sign from the config.
+	signTag := *sign || localUser != "" || *signFormat != ""
+	if signTag && *lightweight {
+		printError("sign is set in the config, which cannot be used with --lightweight: signed tags are always annotated")
+		os.Exit(1)
+	}
+
+	// The first CHANGELOG is the one other modes than tagging read
+	changelogFile := &changelogs.files[0]
+	moreChangelogs := changelogs.files[1:]
+	if len(moreChangelogs) > 0 {
+		// Only the tag message combines several changelogs; these modes
+		// read or update a single file
+		for _, mode := range []struct {
+			used bool
+			name string
+		}{
+			{command == "retag-all", "retag-all"},
+			{*listTagsFlag, "--list-tags"},
+			{*compare != "", "--compare"},
+			{*lint, "--lint-changelog"},
+			{*audit, "--audit"},
+			{*backfill, "--backfill-tags"},
+			{*reformat, "--reformat"},
+			{*format == "frontmatter", "--format frontmatter"},
+			{*updateChangelog, "--update-changelog"},
+			{*resetUnreleasedFlag, "--reset-unreleased"},
+		} {
+			if mode.used {
+				printError(fmt.Sprintf("%s reads a single CHANGELOG; give --changelog only once", mode.name))
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Check if CHANGELOG file exists. Without --changelog, or a config
+	// value for it, look for the usual alternatives in the repository root.
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		explicit = explicit || f.Name == "changelog"
+	})
+	if tagMessage != "" && explicit {
+		printWarning(fmt.Sprintf("A tag message was given, ignoring the CHANGELOG %s", strings.Join(changelogs.files, ", ")))
+	}
+	if !*lightweight && tagMessage == "" {
+		for _, file := range moreChangelogs {
+			if _, err := os.Stat(file); err != nil {
+				printError(fmt.Sprintf("CHANGELOG file not found: %s", file))
+				os.Exit(1)
+			}
+		}
+	}
+	if _, err := os.Stat(*changelogFile); os.IsNotExist(err) && !*lightweight && tagMessage == "" {
+		if explicit {
+			printError(fmt.Sprintf("CHANGELOG file not found: %s", *changelogFile))
+			os.Exit(1)
+		}
+		detected, err := detectChangelog(root, splitList(*changelogCandidates))
+		switch {
+		case err == nil:
+			*changelogFile = detected
+			printSuccess(fmt.Sprintf("Using CHANGELOG file %s", detected))
+		case *conventional:
+			// The message comes from the commits then
+			printSuccess("No CHANGELOG file, using conventional commits")
+		default:
+			printError(fmt.Sprintf("CHANGELOG file not found: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	entryFormat, err := changelogFormat(*changelogFormatFlag, *changelogFile)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if entryFormat == formatRST && headingLevel == 0 {
+		// reStructuredText titles have no heading level to detect
+		headingLevel = defaultHeadingLevel
+	}
+
+	var headerPattern *regexp.Regexp
+	if *headerPatternFlag != "" {
+		headerPattern, err = compileHeaderPattern(*headerPatternFlag)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if headingLevel == 0 {
+			// The pattern decides what a header is, not the heading level
+			headingLevel = defaultHeadingLevel
+		}
+	}
+
+	if headingLevel == 0 {
+		detected, err := detectHeadingLevel(*changelogFile)
+		switch {
+		case os.IsNotExist(err):
+			// Nothing to detect for --lightweight or --message without a CHANGELOG
+		case err != nil:
+			printWarning(fmt.Sprintf("Could not detect the version heading level, using %d: %v", defaultHeadingLevel, err))
+		case detected == 0:
+			printWarning(fmt.Sprintf("No version headers in %s, using heading level %d", *changelogFile, defaultHeadingLevel))
+		default:
+			headingLevel = detected
+			printSuccess(fmt.Sprintf("Detected version heading level %d (%s)", detected, strings.Repeat("#", detected)))
+		}
+	}
+	extractOpts := extractOptions{headingLevel: headingLevel, maxLines: *maxLines, prefix: *tagPrefix, format: entryFormat, headerPattern: headerPattern}
+
+	if command == "retag-all" {
+		if *noGit || *tagName != "" || *lightweight {
+			printError("retag-all cannot be used with --no-git, --tag or --lightweight")
+			os.Exit(1)
+		}
+		tagOpts := tagOptions{taggerName: *taggerName, taggerEmail: *taggerEmail, sign: signTag, keyID: localUser, signFormat: *signFormat, sshKey: *sshKey}
+		summary, err := runRetagAll(*changelogFile, extractOpts, tagOpts, *force, *dryRun)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		printSuccess("Retagged: " + summary.String())
+		if *dryRun {
+			printSuccess("Dry run: no changes made")
+		}
+		os.Exit(0)
+	}
+
+	if *listTagsFlag {
+		if err := runListTags(*changelogFile, extractOpts, *filter, *format, *output); err != nil {
+			printError(fmt.Sprintf("Listing tags failed: %v", err))
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *compare != "" {
+		from, to, err := compareVersions(*compare, flag.Args())
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if err := runCompare(from, to, *changelogFile, extractOpts, *format, *output); err != nil {
+			printError(fmt.Sprintf("Compare failed: %v", err))
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *lint {
+		issues, err := lintChangelog(*changelogFile, extractOpts, *allowFutureDates)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to lint CHANGELOG: %v", err))
+			os.Exit(1)
+		}
+		for _, issue := range issues {
+			printWarning(fmt.Sprintf("%s:%d: %s", *changelogFile, issue.Line, issue.Message))
+		}
+		if len(issues) > 0 {
+			printError(fmt.Sprintf("Found %d problem(s) in %s", len(issues), *changelogFile))
+			os.Exit(1)
+		}
+		printSuccess(fmt.Sprintf("No problems found in %s", *changelogFile))
+		os.Exit(0)
+	}
+
+	if *audit {
+		if err := runAudit(*changelogFile, extractOpts, *format, *output); err != nil {
+			printError(fmt.Sprintf("Audit failed: %v", err))
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// The commit to tag: HEAD, or the commit of the --retag-from tag.
+	// commitName describes it in messages.
+	commit, sharedCommit, commitName := "HEAD", "", "HEAD"
+	if *retagFrom != "" {
+		var err error
+		commit, sharedCommit, err = retagTarget(*retagFrom)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		commitName = fmt.Sprintf("commit %s of '%s'", sharedCommit, *retagFrom)
+		printSuccess("Tagging " + commitName)
+	}
+	if *commitFlag != "" {
+		if _, err := resolveCommit(*commitFlag); err != nil {
+			printError(fmt.Sprintf("'%s' is not a commit in this repository: %v", *commitFlag, err))
+			os.Exit(1)
+		}
+		commit, commitName = *commitFlag, fmt.Sprintf("'%s'", *commitFlag)
+		if short, err := shortCommit(commit); err == nil && short != commit {
+			printSuccess(fmt.Sprintf("Tagging commit %s (%s)", short, commit))
+		} else {
+			printSuccess(fmt.Sprintf("Tagging commit %s", commit))
+		}
+	}
+
+	if *reachableFrom != "" && *batchFile == "" {
+		if err := checkReachable(commit, *reachableFrom); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *requireClean {
+		clean, dirty, err := isWorkingTreeClean()
+		switch {
+		case err != nil:
+			printError(fmt.Sprintf("Failed to check the working tree: %v", err))
+			os.Exit(1)
+		case !clean:
+			printError(fmt.Sprintf("The working tree has uncommitted changes, commit or stash them before tagging:\n  %s", strings.Join(dirty, "\n  ")))
+			os.Exit(1)
+		}
+		printSuccess("The working tree is clean")
+	}
+
+	if *requireUpToDate {
+		// A dry run compares with the last fetched state to avoid writing refs
+		fetch := !*dryRun
+		if fetch {
+			timer.done("checks")
+		}
+		if err := checkUpToDate(fetch); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if fetch {
+			timer.done("fetch")
+		}
+		printSuccess("HEAD is up to date with its upstream")
+	}
+
+	// Without an upstream git push --follow-tags has no branch to push
+	if *pushFollow {
+		if err := checkHasUpstream(); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var delims templateDelims
+	if *templateDelimsFlag != "" {
+		delims, err = parseTemplateDelims(*templateDelimsFlag)
+		if err != nil {
+			printError(fmt.Sprintf("Invalid --template-delims: %v", err))
+			os.Exit(1)
+		}
+	}
+	var messageTemplate, footerTemplate *template.Template
+	if *annotateFile != "" {
+		*templateFile = *annotateFile
+	}
+	if *templateFile != "" {
+		messageTemplate, err = parseTemplateFile(*templateFile, delims)
+		if err != nil {
+			printError(fmt.Sprintf("Invalid template: %v", err))
+			os.Exit(1)
+		}
+		if *requirePlaceholder && !referencesField(messageTemplate, "Changelog") {
+			printError(fmt.Sprintf("%s does not use the {{.Changelog}} placeholder", *templateFile))
+			os.Exit(1)
+		}
+	}
+	if *footerFile != "" {
+		footerTemplate, err = parseTemplateFile(*footerFile, delims)
+		if err != nil {
+			printError(fmt.Sprintf("Invalid footer template: %v", err))
+			os.Exit(1)
+		}
+	}
+	var webhookTmpl *template.Template
+	if *webhookTemplate != "" {
+		webhookTmpl, err = parseWebhookTemplate(*webhookTemplate)
+		if err != nil {
+			printError(fmt.Sprintf("Invalid webhook template: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if *reformat || *format == "frontmatter" {
+		entry, err := extractChangelogEntry(*tagName, *changelogFile, extractOpts)
+		if err != nil {
+			printError(fmt.Sprintf("No CHANGELOG entry to print for '%s'", *tagName))
+			os.Exit(1)
+		}
+		if *reformat {
+			var warnings []string
+			entry, warnings = reformatSection(entry)
+			for _, warning := range warnings {
+				printWarning(warning)
+			}
+		}
+		entry += "\n"
+		if *format == "frontmatter" {
+			sections, err := parseChangelogSections(*changelogFile, extractOpts)
+			if err != nil {
+				printError(fmt.Sprintf("Failed to read CHANGELOG: %v", err))
+				os.Exit(1)
+			}
+			entry, err = withFrontMatter(*tagName, sectionDate(sections, extractOpts.version(*tagName)), entry, time.Now())
+			if err != nil {
+				printError(fmt.Sprintf("Failed to write front matter: %v", err))
+				os.Exit(1)
+			}
+		}
+		if err := writeOutput(*output, entry); err != nil {
+			printError(fmt.Sprintf("Failed to write output: %v", err))
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	releaseDate, err := parseReleaseDate(*dateFlag, time.Now())
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	builder := messageBuilder{
+		message:             tagMessage,
+		changelogFile:       *changelogFile,
+		moreChangelogs:      moreChangelogs,
+		fromUnreleased:      *fromUnreleased,
+		releaseDate:         releaseDate,
+		fromGitLog:          *fromGitLog,
+		conventional:        *conventional,
+		requireChangelog:    *requireChangelog,
+		stripHeading:        *stripHeadingFlag,
+		appendMessages:      appendMessages,
+		extract:             extractOpts,
+		forbidMarkers:       splitList(*forbidMarkers),
+		allowedSections:     splitList(*allowedSections),
+		warnUnknownSections: *warnUnknownSections,
+		minBullets:          *minBullets,
+		expectChecksum:      *expectChecksum,
+		messageTemplate:     messageTemplate,
+		footerTemplate:      footerTemplate,
+		appendDiffstat:      *appendDiffstat,
+		normalizeTrailers:   *normalize,
+		subjectMax:          *subjectMax,
+	}
+	if *warnUnknownSections && len(builder.allowedSections) == 0 {
+		builder.allowedSections = keepAChangelogSections
+	}
+
+	if *stampToolVersion {
+		builder.stampTrailer = fmt.Sprintf("%s: gtauto %s", *stampKey, version)
+	}
+
+	tagOpts := tagOptions{
+		taggerName:  *taggerName,
+		taggerEmail: *taggerEmail,
+		sign:        signTag,
+		keyID:       localUser,
+		signFormat:  *signFormat,
+		sshKey:      *sshKey,
+		lightweight: *lightweight,
+	}
+	if *signoff {
+		name, email := *taggerName, *taggerEmail
+		if name == "" || email == "" {
+			identName, identEmail, err := committerIdentity()
+			if err != nil {
+				printError(fmt.Sprintf("Cannot determine the identity for --signoff; set user.name and user.email or use --tagger-name and --tagger-email: %v", err))
+				os.Exit(1)
+			}
+			if name == "" {
+				name = identName
+			}
+			if email == "" {
+				email = identEmail
+			}
+		}
+		builder.signoffTrailer = fmt.Sprintf("Signed-off-by: %s <%s>", name, email)
+	}
+
+	if *batchFile != "" {
+		entries, err := parseBatchFile(*batchFile)
+		if err != nil {
+			printError(fmt.Sprintf("Invalid batch file: %v", err))
+			os.Exit(1)
+		}
+		if !*noValidate {
+			for _, entry := range entries {
+				if err := validateSemver(extractOpts.version(entry.Tag)); err != nil {
+					printError(fmt.Sprintf("Invalid batch file: %v (use --no-validate to allow it)", err))
+					os.Exit(1)
+				}
+			}
+		}
+		opts := batchOptions{
+			force:           *force,
+			forceMove:       *forceMove,
+			noOverwrite:     *noOverwrite,
+			skipIfUnchanged: *skipIfUnchanged,
+			notesDir:        *notesDir,
+			tagOpts:         tagOpts,
+			reachableFrom:   *reachableFrom,
+			delay:           *batchDelay,
+		}
+		if *resume != "" {
+			opts.state, err = loadBatchState(*resume, *restart)
+			if err != nil {
+				printError(fmt.Sprintf("Cannot resume: %v", err))
+				os.Exit(1)
+			}
+		}
+		if err := runBatch(entries, builder, opts); err != nil {
+			printError(err.Error())
+			os.Exit(failureStatus(err))
+		}
+		timer.done("batch")
+		printTimings(timer)
+		os.Exit(0)
+	}
+
+	if *backfill {
+		commits := map[string]string{}
+		if *commitMap != "" {
+			commits, err = parseCommitMap(*commitMap)
+			if err != nil {
+				printError(fmt.Sprintf("Invalid commit map: %v", err))
+				os.Exit(1)
+			}
+		}
+		sections, err := parseChangelogSections(*changelogFile, extractOpts)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to read CHANGELOG: %v", err))
+			os.Exit(1)
+		}
+		rows, err := auditReleases(sections, extractOpts)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to list tags: %v", err))
+			os.Exit(1)
+		}
+		entries, skipped := backfillPlan(rows, commits, extractOpts.prefix)
+		for _, version := range skipped {
+			printWarning(fmt.Sprintf("No commit for '%s' in the commit map, skipping", version))
+		}
+		if len(entries) == 0 {
+			printSuccess("No tags to backfill")
+			os.Exit(0)
+		}
+		if !*noValidate {
+			for _, entry := range entries {
+				if err := validateSemver(extractOpts.version(entry.Tag)); err != nil {
+					printError(fmt.Sprintf("Cannot backfill: %v (use --no-validate to allow it)", err))
+					os.Exit(1)
+				}
+			}
+		}
+
+		fmt.Fprintf(out, "\nTags to create:\n")
+		for _, entry := range entries {
+			fmt.Fprintf(out, "  %s at %s\n", entry.Tag, entry.Commit)
+		}
+		fmt.Fprintln(out)
+		if *dryRun {
+			printSuccess("Dry run: no changes made")
+			os.Exit(0)
+		}
+		if !*force {
+			ok, err := confirm(fmt.Sprintf("Create %d tag(s)?", len(entries)))
+			if err != nil {
+				printError(err.Error())
+				os.Exit(1)
+			}
+			if !ok {
+				fmt.Fprintln(out, "Operation cancelled")
+				os.Exit(0)
+			}
+		}
+
+		opts := batchOptions{
+			notesDir:      *notesDir,
+			tagOpts:       tagOpts,
+			reachableFrom: *reachableFrom,
+			delay:         *batchDelay,
+		}
+		if err := runBatch(entries, builder, opts); err != nil {
+			printError(err.Error())
+			os.Exit(failureStatus(err))
+		}
+		os.Exit(0)
+	}
+
+	// With --from-unreleased or --conventional a missing entry is expected,
+	// not a typo, and --message doesn't read the CHANGELOG
+	if !*fromDescribe && !*lightweight && !*fromUnreleased && !*conventional && tagMessage == "" && !builder.hasEntry(*tagName) {
+		interactive := !*force && !*jsonOut && stdinIsTerminal()
+		if suggested := suggestTag(*tagName, *changelogFile, extractOpts, interactive); suggested != *tagName {
+			*tagName = suggested
+			printSuccess(fmt.Sprintf("Using tag '%s'", *tagName))
+			if *noOverwrite {
+				if err := checkNoOverwrite(*tagName); err != nil {
+					printError(err.Error())
+					os.Exit(1)
+				}
+			}
+		}
+	}
+
+	if *fromDescribe {
+		base, err := describeBaseTag(commit)
+		switch {
+		case err != nil:
+			printError(fmt.Sprintf("Failed to describe %s: %v", commitName, err))
+			os.Exit(1)
+		case base == "":
+			printWarning(fmt.Sprintf("No tags reachable from %s yet, using the CHANGELOG entry for '%s'", commitName, *tagName))
+		default:
+			printSuccess(fmt.Sprintf("Using the CHANGELOG entry of '%s' from git describe", base))
+			builder.sectionVersion = base
+		}
+	}
+
+	timer.done("checks")
+	var changelogEntry string
+	var changelogFound bool
+	switch {
+	case *lightweight:
+		printSuccess("Lightweight tag: skipping CHANGELOG extraction")
+	default:
+		changelogEntry, changelogFound, err = builder.build(*tagName, commit)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(failureStatus(err))
+		}
+	}
+	timer.done("message")
+
+	if warning := changelogPushGate(*tagName, *requireChangelogForPush, changelogFound); warning != "" && (*push || *pushFollow) {
+		printWarning(warning)
+		*push, *pushFollow = false, false
+	}
+
+	if *noGit {
+		// The entry is part of the JSON result unless it should go to a file
+		if !*jsonOut || *output != "" {
+			if err := writeOutput(*output, changelogEntry+"\n"); err != nil {
+				printError(fmt.Sprintf("Failed to write output: %v", err))
+				os.Exit(1)
+			}
+		}
+		if *clipboard && !*jsonOut && !quiet {
+			if err := copyToClipboard(changelogEntry); err != nil {
+				printWarning(fmt.Sprintf("Could not copy the tag message to the clipboard: %v", err))
+			} else {
+				printSuccess("Copied the tag message to the clipboard")
+			}
+		}
+		report.finish(runResult{Tag: *tagName, Message: changelogEntry, ChangelogFound: changelogFound, Timings: timer.milliseconds()})
+		os.Exit(0)
+	}
+
+	// Check if tag already exists; it is deleted only once the release gate passed
+	replacing := false
+	if tagExists(*tagName) {
+		moved, err := checkTagMove(*tagName, commit, *forceMove)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if *skipIfUnchanged && !moved {
+			unchanged, err := tagMessageUnchanged(*tagName, changelogEntry)
+			if err != nil {
+				printError(fmt.Sprintf("Failed to read existing tag: %v", err))
+				os.Exit(1)
+			}
+			if unchanged {
+				printSuccess(fmt.Sprintf("Tag '%s' already has this message, nothing to do", *tagName))
+				if err := writeMessageOutput(*output, changelogEntry); err != nil {
+					printError(fmt.Sprintf("Failed to write output: %v", err))
+					os.Exit(1)
+				}
+				if *githubOutput {
+					reportGitHubOutput(*tagName, false, changelogEntry)
+				}
+				report.finish(runResult{Tag: *tagName, Message: changelogEntry, ChangelogFound: changelogFound, Timings: timer.milliseconds()})
+				os.Exit(0)
+			}
+		}
+		if *dryRun {
+			printWarning(fmt.Sprintf("Tag '%s' already exists, would overwrite existing tag", *tagName))
+		} else if !*force {
+			// There is nobody to answer a prompt in --json mode; confirm
+			// itself refuses to ask without a terminal, as in CI
+			if *jsonOut {
+				printError(fmt.Sprintf("Tag '%s' already exists; use --force to overwrite it", *tagName))
+				os.Exit(1)
+			}
+			printWarning(fmt.Sprintf("Tag '%s' already exists", *tagName))
+			ok, err := confirmOverwrite()
+			if err != nil {
+				printError(fmt.Sprintf("Cannot overwrite tag '%s': %v", *tagName, err))
+				os.Exit(1)
+			}
+			if !ok {
+				fmt.Fprintln(out, "Operation cancelled")
+				if *githubOutput {
+					reportGitHubOutput(*tagName, false, changelogEntry)
+				}
+				os.Exit(0)
+			}
+		}
+		replacing = true
+	}
+	// Leave the time spent at the overwrite prompt out of the next phase
+	timer.skip()
+
+	if *verifyCommand != "" && *dryRun {
+		printSuccess(fmt.Sprintf("Would run release gate: %s", *verifyCommand))
+	} else if *verifyCommand != "" {
+		fullCommit, err := resolveCommit(commit)
+		if err != nil {
+			printError(fmt.Sprintf("Cannot resolve the commit to verify: %v", err))
+			os.Exit(1)
+		}
+		printSuccess(fmt.Sprintf("Running release gate: %s", *verifyCommand))
+		// Keep the gate's output visible in --json mode, away from stdout
+		gateOut := out
+		if *jsonOut {
+			gateOut = os.Stderr
+		}
+		if err := runVerifyCommand(*verifyCommand, *tagName, fullCommit, gateOut); err != nil {
+			printError(fmt.Sprintf("Release gate failed, not tagging '%s': %v", *tagName, err))
+			os.Exit(exitVerifyFailed)
+		}
+		printSuccess("Release gate passed")
+		timer.done("verify")
+	}
+
+	if replacing && !*dryRun {
+		if err := deleteTag(*tagName); err != nil {
+			printError(fmt.Sprintf("Failed to delete existing tag: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	// Create annotated tag
+	if *dryRun {
+		printSuccess(fmt.Sprintf("Would create tag '%s'", *tagName))
+	} else {
+		printSuccess(fmt.Sprintf("Creating tag '%s'...", *tagName))
+	}
+	if !*lightweight {
+		preview := messagePreview(changelogEntry)
+		if !*usePager || !page(out, preview) {
+			fmt.Fprint(out, preview)
+		}
+	}
+
+	if *retagFrom != "" || *commitFlag != "" {
+		tagOpts.commit = commit
+	}
+	if *dryRun {
+		var commands [][]string
+		if replacing {
+			commands = append(commands, []string{"git", "tag", "-d", *tagName})
+		}
+		commands = append(commands, append([]string{"git"}, tagArgs(*tagName, changelogEntry, tagOpts)...))
+		if *push {
+			commands = append(commands, []string{"git", "push", *remote, "refs/tags/" + *tagName})
+		}
+		if *pushFollow {
+			commands = append(commands, []string{"git", "push", "--follow-tags", *remote})
+		}
+
+		if *format == "json" {
+			fullCommit, err := resolveCommit(commit)
+			if err != nil {
+				printError(fmt.Sprintf("Cannot resolve the commit to tag: %v", err))
+				os.Exit(1)
+			}
+			w, err := openOutput(*output)
+			if err == nil {
+				err = writeDryRunPlan(w, dryRunPlan{
+					Tag:            *tagName,
+					Commit:         fullCommit,
+					Overwrite:      replacing,
+					ChangelogFound: changelogFound,
+					Message:        changelogEntry,
+					Commands:       commands,
+					Flags:          setFlags(flag.CommandLine),
+				})
+				if closeErr := w.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				printError(fmt.Sprintf("Failed to write the dry-run plan: %v", err))
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+
+		for _, command := range commands {
+			printSuccess("Would run: " + formatCommand(command...))
+		}
+		if *webhookURL != "" {
+			printSuccess(fmt.Sprintf("Would notify webhook %s", *webhookURL))
+		}
+		if err := writeMessageOutput(*output, changelogEntry); err != nil {
+			printError(fmt.Sprintf("Failed to write output: %v", err))
+			os.Exit(1)
+		}
+		printSuccess("Dry run: no changes made")
+		printTimings(timer)
+		os.Exit(0)
+	}
+	if err := createTag(*tagName, changelogEntry, tagOpts); err != nil {
+		printError(fmt.Sprintf("Failed to create tag: %v", err))
+		os.Exit(1)
+	}
+
+	printSuccess(fmt.Sprintf("✓ Tag '%s' created successfully", *tagName))
+	// git tag -s signs and writes the tag in one go, and gpg takes most of
+	// the time, so a signed tag is timed as the sign phase
+	if tagOpts.sign {
+		timer.done("sign")
+	} else {
+		timer.done("tag")
+	}
+	if err := writeMessageOutput(*output, changelogEntry); err != nil {
+		printError(fmt.Sprintf("Failed to write output: %v", err))
+		os.Exit(1)
+	}
+
+	if *push {
+		printSuccess(fmt.Sprintf("Pushing tag '%s' to '%s'...", *tagName, *remote))
+		if err := pushTag(*remote, *tagName); err != nil {
+			printError(fmt.Sprintf("Failed to push tag: %v", err))
+			printWarning(fmt.Sprintf("The local tag '%s' was kept; push it later with: git push %s %s", *tagName, *remote, *tagName))
+			os.Exit(1)
+		}
+		printSuccess(fmt.Sprintf("✓ Pushed tag '%s' to '%s'", *tagName, *remote))
+		timer.done("push")
+	}
+	if *pushFollow {
+		printSuccess(fmt.Sprintf("Pushing the current branch and tag '%s' to '%s'...", *tagName, *remote))
+		if err := pushFollowTags(*remote); err != nil {
+			printError(fmt.Sprintf("Failed to push: %v", err))
+			printWarning(fmt.Sprintf("The local tag '%s' was kept; push it later with: git push --follow-tags %s", *tagName, *remote))
+			os.Exit(1)
+		}
+		printSuccess(fmt.Sprintf("✓ Pushed the current branch and tag '%s' to '%s'", *tagName, *remote))
+		timer.done("push")
+	}
+	if *retagFrom != "" {
+		printSuccess(fmt.Sprintf("'%s' and '%s' both point to commit %s", *tagName, *retagFrom, sharedCommit))
+	}
+	if *githubOutput {
+		reportGitHubOutput(*tagName, true, changelogEntry)
+	}
+	if *webhookURL != "" {
+		fullCommit, err := resolveCommit(*tagName)
+		if err == nil {
+			err = notifyWebhook(*webhookURL, webhookTmpl, webhookPayload{
+				Tag:     *tagName,
+				Message: changelogEntry,
+				Repo:    repositoryName(*remote),
+				Commit:  fullCommit,
+			})
+		}
+		switch {
+		case err == nil:
+			printSuccess(fmt.Sprintf("Notified webhook %s", *webhookURL))
+		case *webhookRequired:
+			printError(fmt.Sprintf("Webhook notification failed: %v", err))
+			os.Exit(1)
+		default:
+			printWarning(fmt.Sprintf("Webhook notification failed: %v", err))
+		}
+		timer.done("webhook")
+	}
+	if *recordConfig {
+		if err := recordRelease(*recordTagKey, *recordDateKey, *tagName, time.Now()); err != nil {
+			printWarning(fmt.Sprintf("Could not record the release in git config: %v", err))
+		} else {
+			printSuccess(fmt.Sprintf("Recorded '%s' in git config as %s", *tagName, *recordTagKey))
+		}
+	}
+	if *clipboard && !*jsonOut && !quiet {
+		if err := copyToClipboard(changelogEntry); err != nil {
+			printWarning(fmt.Sprintf("Could not copy the tag message to the clipboard: %v", err))
+		} else {
+			printSuccess("Copied the tag message to the clipboard")
+		}
+	}
+
+	if *updateChangelog {
+		promoted, err := promoteUnreleased(*changelogFile, extractOpts, *tagName, releaseDate)
+		switch {
+		case err != nil:
+			printError(fmt.Sprintf("Failed to update CHANGELOG: %v", err))
+			os.Exit(1)
+		case promoted:
+			printSuccess(fmt.Sprintf("Renamed [Unreleased] to [%s] in %s", *tagName, *changelogFile))
+		default:
+			printWarning(fmt.Sprintf("%s already has a section for '%s' or no [Unreleased] section, not updated", *changelogFile, *tagName))
+		}
+	}
+	if *resetUnreleasedFlag {
+		inserted, err := resetUnreleased(*changelogFile, extractOpts, splitList(*unreleasedSections))
+		switch {
+		case err != nil:
+			printError(fmt.Sprintf("Failed to add [Unreleased] section: %v", err))
+			os.Exit(1)
+		case inserted:
+			printSuccess(fmt.Sprintf("Added empty [Unreleased] section to %s", *changelogFile))
+		default:
+			printWarning(fmt.Sprintf("%s already has an [Unreleased] section", *changelogFile))
+		}
+	}
+	if !*push && !*pushFollow {
+		fmt.Fprintln(out, "\nTo push this tag to remote:")
+		fmt.Fprintf(out, "  git push %s %s\n", *remote, *tagName)
+		fmt.Fprintln(out, "\nTo push all tags:")
+		fmt.Fprintln(out, "  git push --tags")
+	}
+
+	if timer != nil {
+		timer.done("post-tag")
+		fmt.Fprintln(out)
+		printTimings(timer)
+	}
 
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "gtauto: Git tag automation with CHANGELOG support\n\n")
-		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  gtauto --tag <tag_name> [options]\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0\n")
-		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --changelog path/to/CHANGELOG.md\n")
-		fmt.Fprintf(os.Stderr, "  gtauto --tag v1.0.0 --force\n")
+	if *printAfter {
+		info, err := tagInfo(*tagName)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to read back tag message: %v", err))
+			os.Exit(1)
+		}
+		fmt.Println(info.Message)
 	}
 
-	flag.Parse()
+	report.finish(runResult{Tag: *tagName, Created: true, Message: changelogEntry, ChangelogFound: changelogFound, Timings: timer.milliseconds()})
+}
 
-	if *showHelp || *showHelpLong {
-		flag.Usage()
-		os.Exit(0)
+// messagePreview frames the tag message for display before tagging
+func messagePreview(message string) string {
+	separator := strings.Repeat("-", 40)
+	return fmt.Sprintf("\nTag message:\n%s\n%s\n%s\n\n", separator, message, separator)
+}
+
+func checkGitRepository() error {
+	_, err := runGit("rev-parse", "--git-dir")
+	return err
+}
+
+func tagExists(tagName string) bool {
+	output, err := runGit("tag", "-l", tagName)
+	if err != nil {
+		return false
 	}
+	return strings.TrimSpace(string(output)) == tagName
+}
 
-	if *showVersion {
-		fmt.Printf("gtauto version %s\n", version)
-		os.Exit(0)
+// checkNoOverwrite fails if tagName already exists, for --no-overwrite
+func checkNoOverwrite(tagName string) error {
+	if tagExists(tagName) {
+		return fmt.Errorf("tag '%s' already exists and --no-overwrite is set", tagName)
 	}
+	return nil
+}
 
-	if *tagName == "" {
-		printError("--tag option is required")
-		flag.Usage()
-		os.Exit(1)
+func deleteTag(tagName string) error {
+	_, err := runGit("tag", "-d", tagName)
+	return err
+}
+
+// checkTagMove compares the commit of the existing tag tagName with commit.
+// If they differ it fails unless forceMove is set, in which case it warns
+// and reports true.
+func checkTagMove(tagName, commit string, forceMove bool) (bool, error) {
+	info, err := tagInfo(tagName)
+	if err != nil {
+		return false, fmt.Errorf("failed to read existing tag '%s': %v", tagName, err)
+	}
+	target, err := resolveCommit(commit)
+	if err != nil {
+		return false, fmt.Errorf("cannot resolve commit '%s': %v", commit, err)
+	}
+	if info.Commit == target {
+		return false, nil
 	}
 
-	// Check if we're in a git repository
-	if err := checkGitRepository(); err != nil {
-		printError(fmt.Sprintf("Not a git repository: %v", err))
-		os.Exit(1)
+	from, to := info.Commit, target
+	if short, err := shortCommit(from); err == nil {
+		from = short
 	}
+	if short, err := shortCommit(to); err == nil {
+		to = short
+	}
+	if !forceMove {
+		return false, fmt.Errorf("tag '%s' points to commit %s, not %s; use --force-move to move it", tagName, from, to)
+	}
+	printWarning(fmt.Sprintf("Moving tag '%s' from commit %s to %s", tagName, from, to))
+	return true, nil
+}
 
-	// Check if CHANGELOG file exists
-	if _, err := os.Stat(*changelogFile); os.IsNotExist(err) {
-		printError(fmt.Sprintf("CHANGELOG file not found: %s", *changelogFile))
-		os.Exit(1)
+// checkTagName rejects tag names that are empty once normalized, such as
+// whitespace or a bare "v" that leaves no version to match
+func checkTagName(tagName string) error {
+	if strings.TrimSpace(tagName) == "" {
+		return fmt.Errorf("tag name is empty")
 	}
+	if strings.TrimSpace(strings.TrimPrefix(tagName, "v")) == "" {
+		return fmt.Errorf("tag name '%s' has no version after removing the 'v' prefix", tagName)
+	}
+	return nil
+}
 
-	// Check if tag already exists
-	if tagExists(*tagName) {
-		if !*force {
-			printWarning(fmt.Sprintf("Tag '%s' already exists", *tagName))
-			if !confirmOverwrite() {
-				fmt.Println("Operation cancelled")
-				os.Exit(0)
+// shortTagName strips a leading "refs/tags/" from a fully-qualified tag ref
+func shortTagName(name string) string {
+	return strings.TrimPrefix(name, "refs/tags/")
+}
+
+// subcommands are the commands that may be given as the first argument, as
+// in "gtauto list"
+var subcommands = []string{"list", "delete", "retag-all"}
+
+// splitSubcommand returns the subcommand that args start with, or "" if they
+// don't, and the remaining arguments to parse as flags
+func splitSubcommand(args []string) (string, []string) {
+	if len(args) > 0 && contains(subcommands, args[0]) {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+// flagAliases maps the flags that are alternative names to the flag that
+// flagRules use
+var flagAliases = map[string]string{
+	"m":           "message",
+	"signing-key": "local-user",
+	"h":           "help",
+}
+
+// givenFlags returns the names of the flags set in fs, with aliases replaced
+// by the names in flagRules
+func givenFlags(fs *flag.FlagSet) map[string]bool {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		if name, ok := flagAliases[f.Name]; ok {
+			given[name] = true
+		} else {
+			given[f.Name] = true
+		}
+	})
+	return given
+}
+
+// checkFlagRules returns an error for the first of rules that the flags in
+// given break, after checking that --no-git is not combined with a flag that
+// needs git
+func checkFlagRules(rules []flagRule, given map[string]bool) error {
+	if given["no-git"] {
+		if used := gitOnlyFlagsUsed(given); len(used) > 0 {
+			return fmt.Errorf("--no-git cannot be combined with flags that need git: %s", joinFlags(used, "and"))
+		}
+	}
+	for _, rule := range rules {
+		if !given[rule.name] {
+			continue
+		}
+		var problem string
+		var conflicting []string
+		for _, name := range rule.conflicts {
+			if given[name] {
+				conflicting = append(conflicting, name)
 			}
 		}
-		// Delete existing tag
-		if err := deleteTag(*tagName); err != nil {
-			printError(fmt.Sprintf("Failed to delete existing tag: %v", err))
-			os.Exit(1)
+		if len(conflicting) > 0 {
+			problem = fmt.Sprintf("--%s cannot be used with %s", rule.name, joinFlags(conflicting, "or"))
+		} else if len(rule.requires) > 0 && !containsAny(given, rule.requires) {
+			problem = fmt.Sprintf("--%s requires %s", rule.name, joinFlags(rule.requires, "or"))
 		}
+		if problem == "" {
+			continue
+		}
+		if rule.reason != "" {
+			problem += "; " + rule.reason
+		}
+		return errors.New(problem)
 	}
+	return nil
+}
 
-	printSuccess(fmt.Sprintf("Extracting CHANGELOG entry for '%s'...", *tagName))
+// containsAny reports whether any of names is in set
+func containsAny(set map[string]bool, names []string) bool {
+	for _, name := range names {
+		if set[name] {
+			return true
+		}
+	}
+	return false
+}
 
-	// Extract changelog entry
-	changelogEntry, err := extractChangelogEntry(*tagName, *changelogFile)
-	if err != nil {
-		printWarning(fmt.Sprintf("Could not find CHANGELOG entry for '%s'", *tagName))
-		changelogEntry = fmt.Sprintf("Release %s", *tagName)
-	} else {
-		printSuccess("Found CHANGELOG entry")
+// joinFlags renders names as "--a, --b or --c", with conjunction before the
+// last one
+func joinFlags(names []string, conjunction string) string {
+	flags := make([]string, len(names))
+	for i, name := range names {
+		flags[i] = "--" + name
+	}
+	if len(flags) == 1 {
+		return flags[0]
 	}
+	return strings.Join(flags[:len(flags)-1], ", ") + " " + conjunction + " " + flags[len(flags)-1]
+}
 
-	// Create annotated tag
-	printSuccess(fmt.Sprintf("Creating tag '%s'...", *tagName))
-	fmt.Println("\nTag message:")
-	fmt.Println(strings.Repeat("-", 40))
-	fmt.Println(changelogEntry)
-	fmt.Println(strings.Repeat("-", 40))
-	fmt.Println()
-
-	if err := createTag(*tagName, changelogEntry); err != nil {
-		printError(fmt.Sprintf("Failed to create tag: %v", err))
-		os.Exit(1)
+// gitOnlyFlagsUsed returns the flags in given that need git, in flagRules
+// order. --signoff counts unless both --tagger-name and --tagger-email are
+// given, since it otherwise reads the identity from git.
+func gitOnlyFlagsUsed(given map[string]bool) []string {
+	var used []string
+	for _, rule := range flagRules {
+		if rule.git && given[rule.name] && !contains(used, rule.name) {
+			used = append(used, rule.name)
+		}
+	}
+	if given["signoff"] && !(given["tagger-name"] && given["tagger-email"]) {
+		used = append(used, "signoff")
 	}
+	return used
+}
 
-	printSuccess(fmt.Sprintf("✓ Tag '%s' created successfully", *tagName))
-	fmt.Println("\nTo push this tag to remote:")
-	fmt.Printf("  git push origin %s\n", *tagName)
-	fmt.Println("\nTo push all tags:")
-	fmt.Println("  git push --tags")
+// tagFromBranch derives a tag name from a branch such as "release/v1.2.0"
+// by stripping prefix
+func tagFromBranch(branch, prefix string) (string, error) {
+	if !strings.HasPrefix(branch, prefix) {
+		return "", fmt.Errorf("branch '%s' does not start with '%s'", branch, prefix)
+	}
+	tag := strings.TrimPrefix(branch, prefix)
+	if tag == "" {
+		return "", fmt.Errorf("branch '%s' has no version after '%s'", branch, prefix)
+	}
+	return tag, nil
 }
 
-func checkGitRepository() error {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	return cmd.Run()
+// checkReachable returns an error unless commit is reachable from branch
+func checkReachable(commit, branch string) error {
+	short, err := shortCommit(commit)
+	if err != nil {
+		return fmt.Errorf("cannot resolve commit '%s': %v", commit, err)
+	}
+	reachable, err := isAncestor(commit, branch)
+	if err != nil {
+		return fmt.Errorf("cannot check reachability from branch '%s': %v", branch, err)
+	}
+	if !reachable {
+		return fmt.Errorf("commit %s is not reachable from branch '%s'", short, branch)
+	}
+	return nil
 }
 
-func tagExists(tagName string) bool {
-	cmd := exec.Command("git", "tag", "-l", tagName)
-	output, err := cmd.Output()
+// checkUpToDate fails if HEAD is behind the upstream of the current branch,
+// fetching the upstream first if fetch is set
+func checkUpToDate(fetch bool) error {
+	upstream, err := upstreamBranch()
 	if err != nil {
-		return false
+		return err
 	}
-	return strings.TrimSpace(string(output)) == tagName
+	if upstream == "" {
+		return fmt.Errorf("the current branch has no upstream to compare with; set one with 'git branch --set-upstream-to <remote>/<branch>'")
+	}
+	if fetch {
+		if _, err := runGit("fetch", "--quiet"); err != nil {
+			return fmt.Errorf("failed to fetch '%s': %v", upstream, err)
+		}
+	}
+	behind, err := commitsBehind(upstream)
+	if err != nil {
+		return fmt.Errorf("cannot compare HEAD with '%s': %v", upstream, err)
+	}
+	if behind > 0 {
+		return fmt.Errorf("HEAD is %d commit(s) behind '%s'; pull before tagging", behind, upstream)
+	}
+	return nil
 }
 
-func deleteTag(tagName string) error {
-	cmd := exec.Command("git", "tag", "-d", tagName)
-	return cmd.Run()
+// stdinIsTerminal reports whether stdin can answer a question; tests that
+// answer through a pipe replace it
+var stdinIsTerminal = func() bool {
+	return isTerminal(os.Stdin)
 }
 
-func confirmOverwrite() bool {
+func confirmOverwrite() (bool, error) {
+	return confirm("Do you want to overwrite it?")
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no. Without a
+// terminal on stdin, as in CI, nobody can answer; rather than read a piped
+// answer or block, it fails with an error that points to --force.
+func confirm(question string) (bool, error) {
+	if !stdinIsTerminal() {
+		return false, fmt.Errorf("cannot ask %q: stdin is not a terminal; use --force to go ahead without asking", question)
+	}
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Do you want to overwrite it? (y/N): ")
+	// A question must be seen even with --quiet
+	w := out
+	if quiet {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "%s (y/N): ", question)
 	response, err := reader.ReadString('\n')
 	if err != nil {
-		return false
+		return false, nil
 	}
 	response = strings.ToLower(strings.TrimSpace(response))
-	return response == "y" || response == "yes"
+	return response == "y" || response == "yes", nil
+}
+
+// suggestTag checks that tagName has a changelog section and, if it
+// doesn't, looks for the version it most likely meant. When interactive the
+// user may switch to that version; otherwise it is only named in a
+// warning. It returns the tag name to use.
+func suggestTag(tagName, changelogFile string, opts extractOptions, interactive bool) string {
+	if _, err := extractChangelogEntry(tagName, changelogFile, opts); err == nil {
+		return tagName
+	}
+	sections, err := parseChangelogSections(changelogFile, opts)
+	if err != nil {
+		return tagName
+	}
+	versions := make([]string, 0, len(sections))
+	for _, section := range sections {
+		versions = append(versions, section.Version)
+	}
+	version := opts.version(tagName)
+	suggestion := closestVersion(version, versions)
+	if suggestion == "" {
+		return tagName
+	}
+
+	// Keep the component prefix and "v" prefix style of the tag being created
+	suggestion = strings.TrimPrefix(suggestion, "v")
+	if strings.HasPrefix(version, "v") {
+		suggestion = "v" + suggestion
+	}
+	suggestion = opts.prefix + suggestion
+	question := fmt.Sprintf("No CHANGELOG entry for '%s'. Did you mean %s?", tagName, suggestion)
+	if !interactive {
+		printWarning(question)
+		return tagName
+	}
+	// interactive implies a terminal, so confirm cannot fail
+	if ok, _ := confirm(question); ok {
+		return suggestion
+	}
+	return tagName
+}
+
+// extractOptions controls how version sections are located in a changelog
+type extractOptions struct {
+	// headingLevel is the markdown heading level of version headers.
+	// Zero means defaultHeadingLevel.
+	headingLevel int
+	// maxLines is the most lines extraction scans. Zero means
+	// defaultMaxLines.
+	maxLines int
+	// prefix is the component prefix of tag names, e.g. "frontend-" for
+	// "frontend-v1.2.0", which changelog headers leave out
+	prefix string
+	// format is the changelog format, formatMarkdown or formatRST. Empty
+	// means formatMarkdown.
+	format string
+	// headerPattern, when set, matches version headers instead of the
+	// built-in patterns of format, capturing the version in its "version"
+	// group. See compileHeaderPattern.
+	headerPattern *regexp.Regexp
+}
+
+// version returns tagName without the component prefix, as written in
+// changelog headers
+func (o extractOptions) version(tagName string) string {
+	return strings.TrimPrefix(tagName, o.prefix)
+}
+
+// level returns the markdown heading level of version headers
+func (o extractOptions) level() int {
+	if o.headingLevel == 0 {
+		return defaultHeadingLevel
+	}
+	return o.headingLevel
+}
+
+// heading returns the markdown heading prefix for version headers, e.g. "##"
+func (o extractOptions) heading() string {
+	return strings.Repeat("#", o.level())
+}
+
+// errScanLimit is returned by extractChangelogEntry when the entry does not
+// end within the maxLines limit. Reaching the limit before the entry starts
+// is reported as the version not being found.
+var errScanLimit = errors.New("scan limit reached")
+
+// linkReferenceRegex matches markdown link reference definitions such as
+// "[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0"
+var linkReferenceRegex = regexp.MustCompile(`^\[.+\]:\s+https?://`)
+
+// headerReferencePattern matches the optional "[ref]" of a reference-linked
+// version header such as "## [1.0.0][v1.0.0-link]", or the "(url)" of an
+// inline-linked one such as "## [1.0.0](https://example.com/v1.0.0)"
+const headerReferencePattern = `(?:\[[^\]]*\]|\([^)]*\))?`
+
+// versionStartPattern matches the start of a version header after the
+// markdown heading: an optional opening bracket, with any spaces inside it as
+// in "## [ v1.0.0 ]", and an optional "v" or "V" prefix
+const versionStartPattern = `\[?\s*[vV]?`
+
+// versionEndPattern requires the version in a header to end there, so that
+// "1.0.0" doesn't match "## 1.0.0-rc.1" or "## 1.0.01"; a date or any other
+// suffix may follow after a separator
+const versionEndPattern = `(?:$|[^0-9A-Za-z.+-])`
+
+// versionMatchPattern returns the regular expression matching version in a
+// changelog header. Changelogs usually leave out SemVer build metadata, so
+// "1.2.0+build.5" matches "1.2.0" as well as "1.2.0+build.5".
+func versionMatchPattern(version string) string {
+	if core, metadata, ok := strings.Cut(version, "+"); ok {
+		return fmt.Sprintf(`%s(?:\+%s)?`, regexp.QuoteMeta(core), regexp.QuoteMeta(metadata))
+	}
+	return regexp.QuoteMeta(version)
+}
+
+// headerVersionGroup is the named group of --header-pattern that captures
+// the version of a header
+const headerVersionGroup = "version"
+
+// compileHeaderPattern compiles the --header-pattern regular expression,
+// which must capture the version in a (?P<version>...) group
+func compileHeaderPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --header-pattern: %w", err)
+	}
+	if re.SubexpIndex(headerVersionGroup) < 0 {
+		return nil, fmt.Errorf("--header-pattern %q has no (?P<%s>...) group", pattern, headerVersionGroup)
+	}
+	return re, nil
 }
 
-func extractChangelogEntry(tagName, changelogFile string) (string, error) {
+func extractChangelogEntry(tagName, changelogFile string, opts extractOptions) (string, error) {
+	if opts.format == formatRST && opts.headerPattern == nil {
+		return extractChangelogEntryRST(tagName, changelogFile, opts)
+	}
+
 	file, err := os.Open(changelogFile)
 	if err != nil {
 		return "", err
@@ -156,24 +1878,54 @@ func extractChangelogEntry(tagName, changelogFile string) (string, error) {
 		_ = file.Close()
 	}()
 
-	// Remove 'v' prefix if present to match version number
-	version := strings.TrimPrefix(tagName, "v")
+	// Remove the component and 'v' prefixes to match the version number
+	version := strings.TrimPrefix(opts.version(tagName), "v")
+
+	versionMatch := versionMatchPattern(version)
 
-	// Pattern to match version headers like ## [v1.0.0] or ## v1.0.0
-	versionPattern := fmt.Sprintf(`^##\s+\[?v?%s\]?`, regexp.QuoteMeta(version))
+	heading := opts.heading()
+
+	// Pattern to match version headers like ## [v1.0.0] or ## v1.0.0, optionally
+	// followed by a link as in ## [1.0.0][v1.0.0-link] or ## [1.0.0](url).
+	// Case is ignored, so "## [V1.0.0-RC.1]" matches v1.0.0-rc.1.
+	versionPattern := fmt.Sprintf(`(?i)^%s\s+%s%s\s*\]?%s%s`, heading, versionStartPattern, versionMatch, headerReferencePattern, versionEndPattern)
 	versionRegex := regexp.MustCompile(versionPattern)
-	nextVersionRegex := regexp.MustCompile(`^##\s+\[?v?[0-9]+\.[0-9]+`)
+	nextVersionRegex := regexp.MustCompile(fmt.Sprintf(`^%s\s+%s[0-9]+\.[0-9]+[^\]\s]*\s*\]?%s`, heading, versionStartPattern, headerReferencePattern))
+	isEntryHeader, isNextHeader := versionRegex.MatchString, nextVersionRegex.MatchString
+
+	if opts.headerPattern != nil {
+		// Any header ends the entry; the one for version starts it when the
+		// whole captured version matches
+		capturedRegex := regexp.MustCompile(fmt.Sprintf(`(?i)^v?%s$`, versionMatch))
+		group := opts.headerPattern.SubexpIndex(headerVersionGroup)
+		isNextHeader = opts.headerPattern.MatchString
+		isEntryHeader = func(line string) bool {
+			match := opts.headerPattern.FindStringSubmatch(line)
+			return match != nil && capturedRegex.MatchString(strings.TrimSpace(match[group]))
+		}
+	}
+
+	maxLines := opts.maxLines
+	if maxLines == 0 {
+		maxLines = defaultMaxLines
+	}
 
 	scanner := bufio.NewScanner(file)
 	var inSection bool
 	var content strings.Builder
 	var sectionFound bool
 
-	for scanner.Scan() {
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if lineNum > maxLines {
+			if !inSection {
+				return "", fmt.Errorf("version %s not found in the first %d lines of the changelog (see --max-lines)", tagName, maxLines)
+			}
+			return "", fmt.Errorf("%w: scanned %d lines of %s without finding the end of the entry for %s; the file may be malformed (see --max-lines)", errScanLimit, maxLines, changelogFile, tagName)
+		}
 		line := scanner.Text()
 
 		// Check if this is the version we're looking for
-		if versionRegex.MatchString(line) {
+		if isEntryHeader(line) {
 			inSection = true
 			sectionFound = true
 			content.WriteString(line)
@@ -181,8 +1933,9 @@ func extractChangelogEntry(tagName, changelogFile string) (string, error) {
 			continue
 		}
 
-		// Check if we've reached the next version section
-		if inSection && nextVersionRegex.MatchString(line) {
+		// Check if we've reached the next version section, or the link
+		// reference definitions that usually close the file
+		if inSection && (isNextHeader(line) || linkReferenceRegex.MatchString(line)) {
 			break
 		}
 
@@ -206,19 +1959,313 @@ func extractChangelogEntry(tagName, changelogFile string) (string, error) {
 	return result, nil
 }
 
-func createTag(tagName, message string) error {
-	cmd := exec.Command("git", "tag", "-a", tagName, "-m", message)
-	return cmd.Run()
+// tagOptions controls how createTag creates a tag
+type tagOptions struct {
+	// commit is the revision to tag; empty means HEAD
+	commit string
+	// taggerName and taggerEmail override the tagger identity when set
+	taggerName  string
+	taggerEmail string
+	// sign creates a GPG-signed tag with keyID, or the default key when
+	// keyID is empty
+	sign  bool
+	keyID string
+	// signFormat sets gpg.format for the signature (openpgp, x509 or ssh)
+	// and sshKey sets user.signingkey to an SSH key file; both apply only
+	// when signing
+	signFormat string
+	sshKey     string
+	// lightweight creates a tag without an annotation; the message is
+	// ignored
+	lightweight bool
+}
+
+// createTag creates an annotated tag, signed if opts.sign is set, or a
+// lightweight tag if opts.lightweight is set
+func createTag(tagName, message string, opts tagOptions) error {
+	if opts.sign {
+		return createSignedTag(tagName, message, opts.keyID, opts)
+	}
+	_, err := gitExec("", tagEnv(opts), tagArgs(tagName, message, opts)...)
+	return err
+}
+
+// createSignedTag creates a GPG-signed annotated tag with keyID, or with
+// git's default signing key when keyID is empty. Signing failures caused by
+// a missing key or gpg setup are explained in the returned error.
+func createSignedTag(tagName, message, keyID string, opts tagOptions) error {
+	opts.sign, opts.keyID = true, keyID
+	if _, err := gitExec("", tagEnv(opts), tagArgs(tagName, message, opts)...); err != nil {
+		return signingError(err)
+	}
+	return nil
+}
+
+// signingFailureMarkers are fragments of git and gpg errors that mean no
+// usable signing key or gpg program is set up
+var signingFailureMarkers = []string{
+	"gpg failed to sign",
+	"secret key not available",
+	"no secret key",
+	"cannot run gpg",
+	"no default secret key",
+	"unusable secret key",
+}
+
+// signingError explains the signing setup when the error of a failed
+// git tag -s, which carries git's standard error, shows a missing key or
+// gpg program
+func signingError(err error) error {
+	lower := strings.ToLower(err.Error())
+	for _, marker := range signingFailureMarkers {
+		if strings.Contains(lower, marker) {
+			return fmt.Errorf("could not sign the tag: no usable GPG key. Set one with 'git config user.signingkey <keyid>' or pass --local-user, and check that gpg.program points to a working gpg (git said: %w)", err)
+		}
+	}
+	return err
+}
+
+// signFormats are the values of git's gpg.format accepted by --sign-format
+var signFormats = []string{"openpgp", "x509", "ssh"}
+
+// checkSSHKey checks that path is an SSH key file git can sign with. It
+// returns a warning for a private key that other users can read, which
+// ssh-keygen refuses to use.
+func checkSSHKey(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot use --ssh-key: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("cannot use --ssh-key: %s is not a regular file", path)
+	}
+	// git also accepts the public key when the private one is in ssh-agent
+	if !strings.HasSuffix(path, ".pub") && info.Mode().Perm()&0o077 != 0 {
+		return fmt.Sprintf("SSH private key %s is accessible by other users (mode %04o); run chmod 600 %s", path, info.Mode().Perm(), path), nil
+	}
+	return "", nil
+}
+
+// tagArgs returns the git arguments that create tagName with message
+func tagArgs(tagName, message string, opts tagOptions) []string {
+	if opts.lightweight {
+		args := []string{"tag", tagName}
+		if opts.commit != "" {
+			args = append(args, opts.commit)
+		}
+		return args
+	}
+	args := []string{"tag", "-a"}
+	switch {
+	case opts.sign && opts.keyID != "":
+		args = []string{"tag", "-u", opts.keyID}
+	case opts.sign:
+		args = []string{"tag", "-s"}
+	}
+	if opts.sign {
+		var config []string
+		if opts.signFormat != "" {
+			config = append(config, "-c", "gpg.format="+opts.signFormat)
+		}
+		if opts.sshKey != "" {
+			config = append(config, "-c", "user.signingkey="+opts.sshKey)
+		}
+		args = append(config, args...)
+	}
+	args = append(args, tagName, "-m", message)
+	if opts.commit != "" {
+		args = append(args, opts.commit)
+	}
+	return args
+}
+
+// shellSafeRegex matches arguments that need no quoting in a POSIX shell
+var shellSafeRegex = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// formatCommand renders args as a command line that can be pasted into a
+// POSIX shell, single-quoting arguments where needed
+func formatCommand(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafeRegex.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// tagEnv returns the environment that sets the tagger identity of opts for
+// git tag; git takes it from the committer variables
+func tagEnv(opts tagOptions) []string {
+	var env []string
+	if opts.taggerName != "" {
+		env = append(env, "GIT_COMMITTER_NAME="+opts.taggerName)
+	}
+	if opts.taggerEmail != "" {
+		env = append(env, "GIT_COMMITTER_EMAIL="+opts.taggerEmail)
+	}
+	return env
+}
+
+// deleteRemoteTag deletes tagName from remote. On failure the error carries
+// git's standard error.
+func deleteRemoteTag(remote, tagName string) error {
+	_, err := runGit("push", remote, ":refs/tags/"+tagName)
+	return err
+}
+
+// changelogPushGate returns the warning that --require-changelog-for-push
+// gives when the message of tagName is not a CHANGELOG entry, so the tag is
+// created but not pushed, or "" if the tag may be pushed
+func changelogPushGate(tagName string, requireEntry, changelogFound bool) string {
+	if !requireEntry || changelogFound {
+		return ""
+	}
+	return fmt.Sprintf("No CHANGELOG entry for '%s', not pushing the tag (--require-changelog-for-push); it is created locally only", tagName)
+}
+
+// pushTag pushes tagName to remote. On failure the error carries git's
+// standard error; the local tag is left in place.
+func pushTag(remote, tagName string) error {
+	_, err := runGit("push", remote, "refs/tags/"+tagName)
+	return err
+}
+
+// pushFollowTags pushes the current branch to remote together with the
+// annotated tags that point into it
+func pushFollowTags(remote string) error {
+	_, err := runGit("push", "--follow-tags", remote)
+	return err
+}
+
+// checkHasUpstream fails unless the current branch has an upstream for
+// --push-follow to push to
+func checkHasUpstream() error {
+	upstream, err := upstreamBranch()
+	if err != nil {
+		return fmt.Errorf("cannot find the upstream of the current branch: %v", err)
+	}
+	if upstream == "" {
+		return fmt.Errorf("the current branch has no upstream for --push-follow; set one with 'git push -u <remote> <branch>', or use --push to push only the tag")
+	}
+	return nil
+}
+
+// openOutput opens path for writing, creating its parent directories; an
+// empty path or "-" selects stdout
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// writeMessageOutput writes the tag message to the --output path, if set,
+// and reports where it went
+func writeMessageOutput(path, message string) error {
+	if path == "" {
+		return nil
+	}
+	if err := writeOutput(path, message+"\n"); err != nil {
+		return err
+	}
+	if path != "-" {
+		printSuccess(fmt.Sprintf("Wrote the tag message to %s", path))
+	}
+	return nil
+}
+
+// writeOutput writes content to path; an empty path or "-" selects stdout
+func writeOutput(path, content string) error {
+	w, err := openOutput(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// stringList is a flag that may be given several times, collecting each
+// value in order
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// changelogFlag is the --changelog flag. The first value, from the command
+// line or a config file, replaces the default and repeating the flag adds
+// more files.
+type changelogFlag struct {
+	files []string
+	set   bool
+}
+
+func (f *changelogFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.files, ", ")
+}
+
+func (f *changelogFlag) Set(value string) error {
+	if !f.set {
+		f.files, f.set = nil, true
+	}
+	f.files = append(f.files, value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// contains reports whether value is one of values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 func printError(message string) {
-	fmt.Printf("%sError: %s%s\n", colorRed, message, colorReset)
+	report.error(message)
 }
 
 func printWarning(message string) {
-	fmt.Printf("%sWarning: %s%s\n", colorYellow, message, colorReset)
+	report.warning(message)
 }
 
 func printSuccess(message string) {
-	fmt.Printf("%s%s%s\n", colorGreen, message, colorReset)
+	report.success(message)
 }
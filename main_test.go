@@ -1,8 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -121,6 +126,30 @@ func TestExtractChangelogEntry(t *testing.T) {
 - Middle bug`,
 			wantErr: false,
 		},
+		{
+			name:    "preserve ordered list items",
+			tagName: "v1.1.0",
+			changelogContent: `# Changelog
+
+## [v1.1.0] - 2025-08-28
+
+### Added
+1. First feature
+2. Second feature
+   1. Nested detail
+
+## [v1.0.0] - 2025-08-26
+
+### Added
+1. Initial release`,
+			wantContent: `## [v1.1.0] - 2025-08-28
+
+### Added
+1. First feature
+2. Second feature
+   1. Nested detail`,
+			wantErr: false,
+		},
 		{
 			name:    "handle trailing newlines",
 			tagName: "v1.0.0",
@@ -139,6 +168,192 @@ func TestExtractChangelogEntry(t *testing.T) {
 - Initial release`,
 			wantErr: false,
 		},
+		{
+			name:    "stop at trailing link reference definitions",
+			tagName: "v1.0.0",
+			changelogContent: `# Changelog
+
+## [v1.0.1] - 2025-08-27
+
+### Fixed
+- Bug fix 1
+
+## [v1.0.0] - 2025-08-26
+
+### Added
+- Initial release
+
+[v1.0.1]: https://github.com/shivase/gtauto/compare/v1.0.0...v1.0.1
+[v1.0.0]: https://github.com/shivase/gtauto/releases/tag/v1.0.0
+`,
+			wantContent: `## [v1.0.0] - 2025-08-26
+
+### Added
+- Initial release`,
+			wantErr: false,
+		},
+		{
+			name:    "extract version with reference-linked headers",
+			tagName: "v1.1.0",
+			changelogContent: `# Changelog
+
+## [1.1.0][v1.1.0-link] - 2025-09-01
+
+### Fixed
+- Linked fix
+
+## [1.0.0][v1.0.0-link] - 2025-08-26
+
+### Added
+- Initial release
+
+[v1.1.0-link]: https://github.com/owner/repo/compare/v1.0.0...v1.1.0
+[v1.0.0-link]: https://github.com/owner/repo/releases/tag/v1.0.0`,
+			wantContent: `## [1.1.0][v1.1.0-link] - 2025-09-01
+
+### Fixed
+- Linked fix`,
+			wantErr: false,
+		},
+		{
+			name:    "extract version with inline-linked headers",
+			tagName: "v1.2.0",
+			changelogContent: `# Changelog
+
+## [1.2.0](https://github.com/owner/repo/compare/v1.1.0...v1.2.0) (2025-09-10)
+
+### Features
+- Inline link
+
+## [1.1.0](https://github.com/owner/repo/compare/v1.0.0...v1.1.0) - 2025-09-01
+
+### Fixed
+- Linked fix`,
+			wantContent: `## [1.2.0](https://github.com/owner/repo/compare/v1.1.0...v1.2.0) (2025-09-10)
+
+### Features
+- Inline link`,
+			wantErr: false,
+		},
+		{
+			name:    "extract older inline-linked version",
+			tagName: "1.1.0",
+			changelogContent: `## [1.2.0](https://github.com/owner/repo/compare/v1.1.0...v1.2.0)
+- Inline link
+
+## [1.1.0](https://github.com/owner/repo/compare/v1.0.0...v1.1.0)
+- Linked fix`,
+			wantContent: `## [1.1.0](https://github.com/owner/repo/compare/v1.0.0...v1.1.0)
+- Linked fix`,
+			wantErr: false,
+		},
+		{
+			name:    "version followed by a date in parentheses",
+			tagName: "v1.0.0",
+			changelogContent: `## v1.0.0 (2025-08-26)
+- Initial release`,
+			wantContent: `## v1.0.0 (2025-08-26)
+- Initial release`,
+			wantErr: false,
+		},
+		{
+			name:    "pre-release header does not match the release",
+			tagName: "v1.0.0",
+			changelogContent: `## [1.0.0-rc.1](https://github.com/owner/repo/releases/tag/v1.0.0-rc.1)
+- Candidate`,
+			wantErr: true,
+		},
+		{
+			name:    "build metadata matches the plain version",
+			tagName: "v1.2.0+exp.sha.5114f85",
+			changelogContent: `## [1.2.0] - 2025-09-10
+- Release
+
+## [1.1.0] - 2025-09-01
+- Older`,
+			wantContent: `## [1.2.0] - 2025-09-10
+- Release`,
+			wantErr: false,
+		},
+		{
+			name:    "build metadata matches a header with the same metadata",
+			tagName: "1.2.0+build.5",
+			changelogContent: `## 1.2.0+build.5
+- Release`,
+			wantContent: `## 1.2.0+build.5
+- Release`,
+			wantErr: false,
+		},
+		{
+			name:    "build metadata does not match other metadata",
+			tagName: "1.2.0+build.5",
+			changelogContent: `## 1.2.0+build.4
+- Release`,
+			wantErr: true,
+		},
+		{
+			name:    "pre-release with build metadata matches the pre-release",
+			tagName: "v1.2.0-rc.1+build.5",
+			changelogContent: `## [1.2.0] - 2025-09-10
+- Release
+
+## [1.2.0-rc.1] - 2025-09-05
+- Candidate`,
+			wantContent: `## [1.2.0-rc.1] - 2025-09-05
+- Candidate`,
+			wantErr: false,
+		},
+		{
+			name:    "pre-release does not match the release",
+			tagName: "v1.2.0-rc.1",
+			changelogContent: `## [1.2.0] - 2025-09-10
+- Release`,
+			wantErr: true,
+		},
+		{
+			name:    "spaces inside the brackets",
+			tagName: "v1.0.1",
+			changelogContent: `## [ v1.0.1 ] - 2025-08-27
+- Spaced
+
+## [ v1.0.0 ]
+- Initial release`,
+			wantContent: `## [ v1.0.1 ] - 2025-08-27
+- Spaced`,
+		},
+		{
+			name:    "upper case V prefix",
+			tagName: "v1.0.1",
+			changelogContent: `## [V1.0.1]
+- Upper case
+
+## V1.0.0
+- Initial release`,
+			wantContent: `## [V1.0.1]
+- Upper case`,
+		},
+		{
+			name:    "pre-release in another case",
+			tagName: "v1.2.0-rc.1",
+			changelogContent: `## 1.2.0-RC.1 - 2025-09-05
+- Candidate`,
+			wantContent: `## 1.2.0-RC.1 - 2025-09-05
+- Candidate`,
+		},
+		{
+			name:    "spaces inside the brackets of a different version",
+			tagName: "v1.0.0",
+			changelogContent: `## [ v1.0.10 ]
+- Ten`,
+			wantErr: true,
+		},
+		{
+			name:    "longer version does not match",
+			tagName: "v1.0.1",
+			changelogContent: `## [1.0.10] - 2025-09-01
+- Ten`,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -153,7 +368,7 @@ func TestExtractChangelogEntry(t *testing.T) {
 			}
 
 			// Test the extraction
-			got, err := extractChangelogEntry(tt.tagName, changelogFile)
+			got, err := extractChangelogEntry(tt.tagName, changelogFile, extractOptions{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("extractChangelogEntry() error = %v, wantErr %v", err, tt.wantErr)
@@ -173,6 +388,455 @@ func TestExtractChangelogEntry(t *testing.T) {
 	}
 }
 
+func TestExtractChangelogEntryMaxLines(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("# Changelog\n\n## [v1.0.0]\n")
+	for i := 0; i < 100; i++ {
+		b.WriteString("- change\n")
+	}
+	b.WriteString("\n## [v0.9.0]\n- older\n")
+	changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(changelogFile, []byte(b.String()), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+
+	// The entry ends at line 105 with the v0.9.0 header
+	if _, err := extractChangelogEntry("v1.0.0", changelogFile, extractOptions{maxLines: 105}); err != nil {
+		t.Errorf("extractChangelogEntry() within the limit error = %v", err)
+	}
+
+	_, err := extractChangelogEntry("v1.0.0", changelogFile, extractOptions{maxLines: 50})
+	if !errors.Is(err, errScanLimit) || !strings.Contains(err.Error(), "malformed") {
+		t.Errorf("extractChangelogEntry() over the limit error = %v, want errScanLimit", err)
+	}
+
+	builder := messageBuilder{changelogFile: changelogFile, extract: extractOptions{maxLines: 50}}
+	if _, _, err := builder.build("v1.0.0", "HEAD"); !errors.Is(err, errScanLimit) {
+		t.Errorf("build() error = %v, want errScanLimit instead of the fallback message", err)
+	}
+
+	// Reaching the limit before the entry starts means it wasn't found
+	_, err = extractChangelogEntry("v0.9.0", changelogFile, extractOptions{maxLines: 50})
+	if err == nil || errors.Is(err, errScanLimit) || !strings.Contains(err.Error(), "not found in the first 50 lines") {
+		t.Errorf("extractChangelogEntry() of a version past the limit error = %v, want it not found", err)
+	}
+}
+
+func TestExtractChangelogEntryHeadingLevel(t *testing.T) {
+	tests := []struct {
+		name             string
+		headingOffset    int
+		changelogContent string
+		wantContent      string
+		wantErr          bool
+	}{
+		{
+			name:          "offset +1 matches ### headers",
+			headingOffset: 1,
+			changelogContent: `# Project docs
+
+## Changelog
+
+### [v1.0.1] - 2025-08-27
+
+#### Added
+- New feature
+
+### [v1.0.0] - 2025-08-26
+
+#### Added
+- Initial release`,
+			wantContent: `### [v1.0.1] - 2025-08-27
+
+#### Added
+- New feature`,
+			wantErr: false,
+		},
+		{
+			name:          "offset -1 matches # headers",
+			headingOffset: -1,
+			changelogContent: `# [v1.0.1] - 2025-08-27
+
+## Added
+- New feature
+
+# [v1.0.0] - 2025-08-26
+
+## Added
+- Initial release`,
+			wantContent: `# [v1.0.1] - 2025-08-27
+
+## Added
+- New feature`,
+			wantErr: false,
+		},
+		{
+			name:          "offset +1 ignores ## headers",
+			headingOffset: 1,
+			changelogContent: `# Changelog
+
+## [v1.0.1] - 2025-08-27
+
+### Added
+- New feature`,
+			wantContent: "",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if err := os.WriteFile(changelogFile, []byte(tt.changelogContent), 0644); err != nil {
+				t.Fatalf("Failed to create test changelog: %v", err)
+			}
+
+			opts := extractOptions{headingLevel: defaultHeadingLevel + tt.headingOffset}
+			got, err := extractChangelogEntry("v1.0.1", changelogFile, opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("extractChangelogEntry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.wantContent {
+				t.Errorf("extractChangelogEntry() content mismatch\nGot:\n%s\n\nWant:\n%s", got, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"simple list", "Added,Fixed", []string{"Added", "Fixed"}},
+		{"spaces and empty items", " Added , ,Fixed,", []string{"Added", "Fixed"}},
+		{"empty value", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitList(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitList(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTagName(t *testing.T) {
+	tests := []struct {
+		name    string
+		tagName string
+		wantErr bool
+	}{
+		{"regular tag", "v1.0.0", false},
+		{"tag without prefix", "1.0.0", false},
+		{"whitespace only", "  ", true},
+		{"bare v prefix", "v", true},
+		{"v prefix and whitespace", "v ", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkTagName(tt.tagName); (err != nil) != tt.wantErr {
+				t.Errorf("checkTagName(%q) error = %v, wantErr %v", tt.tagName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTagNameFromBranch(t *testing.T) {
+	// release/v reduces to "v", which must be rejected after derivation
+	tag, err := tagFromBranch("release/v", "release/")
+	if err != nil {
+		t.Fatalf("tagFromBranch() error = %v", err)
+	}
+	if err := checkTagName(tag); err == nil {
+		t.Errorf("checkTagName(%q) expected error, got nil", tag)
+	}
+}
+
+func TestShortTagName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"v1.0.0", "v1.0.0"},
+		{"refs/tags/v1.0.0", "v1.0.0"},
+		{"refs/tags/release/v1.0.0", "release/v1.0.0"},
+		{"refs/heads/v1.0.0", "refs/heads/v1.0.0"},
+	}
+
+	for _, tt := range tests {
+		short := shortTagName(tt.name)
+		if short != tt.want {
+			t.Errorf("shortTagName(%q) = %q, want %q", tt.name, short, tt.want)
+		}
+		if err := checkTagName(short); err != nil {
+			t.Errorf("checkTagName(%q) error = %v", short, err)
+		}
+	}
+}
+
+func TestExtractChangelogEntryQualifiedRef(t *testing.T) {
+	changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(changelogFile, []byte("# Changelog\n\n## [v1.0.0]\n- First\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+
+	got, err := extractChangelogEntry(shortTagName("refs/tags/v1.0.0"), changelogFile, extractOptions{})
+	if err != nil {
+		t.Fatalf("extractChangelogEntry() error = %v", err)
+	}
+	if want := "## [v1.0.0]\n- First"; got != want {
+		t.Errorf("extractChangelogEntry() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractChangelogEntryPrefix(t *testing.T) {
+	changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	content := "# Frontend changelog\n\n## [1.2.0] - 2025-09-10\n- Button\n\n## [1.1.0] - 2025-09-01\n- Form\n"
+	if err := os.WriteFile(changelogFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+
+	tests := []struct {
+		tag     string
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{"frontend-v1.2.0", "frontend-", "## [1.2.0] - 2025-09-10\n- Button", false},
+		{"frontend-1.1.0", "frontend-", "## [1.1.0] - 2025-09-01\n- Form", false},
+		{"frontend-v1.2.0", "", "", true},
+		{"backend-v1.2.0", "frontend-", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := extractChangelogEntry(tt.tag, changelogFile, extractOptions{prefix: tt.prefix})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractChangelogEntry(%q) with prefix %q error = %v, wantErr %v", tt.tag, tt.prefix, err, tt.wantErr)
+			continue
+		}
+		if strings.TrimSpace(got) != tt.want {
+			t.Errorf("extractChangelogEntry(%q) with prefix %q = %q, want %q", tt.tag, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestExtractChangelogEntryHeaderPattern(t *testing.T) {
+	changelogFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	content := "Release history\n\nVersion 1.2.0 — 2025-08-27\n- Arrays\n\nVersion 1.2.0-rc.1 — 2025-08-20\n- Preview\n\nVersion 1.1.0 — 2025-08-01\n- Objects\n"
+	if err := os.WriteFile(changelogFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+	pattern, err := compileHeaderPattern(`^Version (?P<version>\S+) — `)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{"v1.2.0", "Version 1.2.0 — 2025-08-27\n- Arrays", false},
+		{"1.2.0-RC.1", "Version 1.2.0-rc.1 — 2025-08-20\n- Preview", false},
+		{"v1.1.0+build.3", "Version 1.1.0 — 2025-08-01\n- Objects", false},
+		{"v1.2", "", true},
+		{"v2.0.0", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := extractChangelogEntry(tt.tag, changelogFile, extractOptions{headerPattern: pattern})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractChangelogEntry(%q) with --header-pattern error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			continue
+		}
+		if strings.TrimSpace(got) != tt.want {
+			t.Errorf("extractChangelogEntry(%q) with --header-pattern = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestCompileHeaderPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{`^Version (?P<version>\S+)`, false},
+		{`^== (?P<version>[0-9.]+) ==$`, false},
+		{`^Version (\S+)`, true},
+		{`^Version (?P<ver>\S+)`, true},
+		{`^Version (?P<version>\S+`, true},
+	}
+
+	for _, tt := range tests {
+		if _, err := compileHeaderPattern(tt.pattern); (err != nil) != tt.wantErr {
+			t.Errorf("compileHeaderPattern(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTagFromBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		branch  string
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{"release branch", "release/v1.2.0", "release/", "v1.2.0", false},
+		{"custom prefix", "rel-1.2.0", "rel-", "1.2.0", false},
+		{"prefix mismatch", "feature/v1.2.0", "release/", "", true},
+		{"prefix only", "release/", "release/", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tagFromBranch(tt.branch, tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tagFromBranch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("tagFromBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSubcommand(t *testing.T) {
+	tests := []struct {
+		args        []string
+		wantCommand string
+		wantArgs    []string
+	}{
+		{[]string{"list"}, "list", []string{}},
+		{[]string{"list", "--filter", "v1.*"}, "list", []string{"--filter", "v1.*"}},
+		{[]string{"retag-all", "--force"}, "retag-all", []string{"--force"}},
+		{[]string{"--tag", "list"}, "", []string{"--tag", "list"}},
+		{[]string{"lists"}, "", []string{"lists"}},
+		{nil, "", nil},
+	}
+
+	for _, tt := range tests {
+		command, args := splitSubcommand(tt.args)
+		if command != tt.wantCommand || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("splitSubcommand(%q) = %q, %q, want %q, %q", tt.args, command, args, tt.wantCommand, tt.wantArgs)
+		}
+	}
+}
+
+func TestGitOnlyFlagsUsed(t *testing.T) {
+	tests := []struct {
+		name     string
+		explicit []string
+		want     []string
+	}{
+		{"extraction only", []string{"tag", "changelog", "output", "no-git"}, nil},
+		{"git flags in order", []string{"no-git", "print-after", "tag-from-branch"}, []string{"tag-from-branch", "print-after"}},
+		{"signoff from git config", []string{"signoff", "tagger-name"}, []string{"signoff"}},
+		{"signoff with explicit identity", []string{"signoff", "tagger-name", "tagger-email"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explicit := make(map[string]bool)
+			for _, name := range tt.explicit {
+				explicit[name] = true
+			}
+			if got := gitOnlyFlagsUsed(explicit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gitOnlyFlagsUsed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFlagRules(t *testing.T) {
+	rules := []flagRule{
+		{name: "push", git: true, conflicts: []string{"push-follow", "batch"}},
+		{name: "resume", requires: []string{"batch"}},
+		{name: "date", requires: []string{"from-unreleased", "conventional"}},
+		{name: "lightweight", conflicts: []string{"sign"}, reason: "signed tags are always annotated"},
+	}
+	tests := []struct {
+		name  string
+		given []string
+		want  string
+	}{
+		{"first broken rule", []string{"push", "resume", "batch", "conventional", "date"}, "--push cannot be used with --batch"},
+		{"nothing given", nil, ""},
+		{"all conflicts named", []string{"push", "push-follow", "batch"}, "--push cannot be used with --push-follow or --batch"},
+		{"requirement missing", []string{"resume"}, "--resume requires --batch"},
+		{"one of several required", []string{"date"}, "--date requires --from-unreleased or --conventional"},
+		{"requirement met", []string{"date", "from-unreleased"}, ""},
+		{"reason", []string{"lightweight", "sign"}, "--lightweight cannot be used with --sign; signed tags are always annotated"},
+		{"conflict in one direction only", []string{"sign", "push-follow"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			given := make(map[string]bool)
+			for _, name := range tt.given {
+				given[name] = true
+			}
+			err := checkFlagRules(rules, given)
+			if got := fmt.Sprint(err); (err == nil) != (tt.want == "") || (err != nil && got != tt.want) {
+				t.Errorf("checkFlagRules() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// --no-git is checked against the git flags of flagRules
+	err := checkFlagRules(nil, map[string]bool{"no-git": true, "push": true, "sign": true})
+	if want := "--no-git cannot be combined with flags that need git: --push and --sign"; fmt.Sprint(err) != want {
+		t.Errorf("checkFlagRules() with --no-git = %v, want %q", err, want)
+	}
+}
+
+func TestLightweightFlagRules(t *testing.T) {
+	// A lightweight tag has no message, so nothing that builds one applies
+	for _, name := range []string{
+		"template", "signoff", "stamp-tool-version", "footer-template", "append-diffstat",
+		"min-bullets", "forbid-markers", "expect-checksum", "allowed-sections", "skip-if-unchanged",
+		"output", "append-message", "message", "sign", "local-user",
+	} {
+		if err := checkFlagRules(flagRules, map[string]bool{"lightweight": true, name: true}); err == nil {
+			t.Errorf("checkFlagRules() accepted --lightweight with --%s", name)
+		}
+	}
+	if err := checkFlagRules(flagRules, map[string]bool{"lightweight": true, "push": true, "no-validate": true}); err != nil {
+		t.Errorf("checkFlagRules() with --lightweight --push error = %v", err)
+	}
+}
+
+func TestGivenFlags(t *testing.T) {
+	fs := flag.NewFlagSet("gtauto", flag.ContinueOnError)
+	var message, localUser string
+	fs.StringVar(&message, "message", "", "")
+	fs.StringVar(&message, "m", "", "")
+	fs.StringVar(&localUser, "signing-key", "", "")
+	fs.Bool("push", false, "")
+	fs.Bool("sign", false, "")
+	if err := fs.Parse([]string{"-m", "Hotfix", "--signing-key", "ABCD", "--push"}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"message": true, "local-user": true, "push": true}
+	if got := givenFlags(fs); !reflect.DeepEqual(got, want) {
+		t.Errorf("givenFlags() = %v, want %v", got, want)
+	}
+}
+
+func TestMessagePreview(t *testing.T) {
+	separator := strings.Repeat("-", 40)
+	want := "\nTag message:\n" + separator + "\n## [v1.0.0]\n\n- Initial release\n" + separator + "\n\n"
+	if got := messagePreview("## [v1.0.0]\n\n- Initial release"); got != want {
+		t.Errorf("messagePreview() = %q, want %q", got, want)
+	}
+}
+
 func TestTagExists(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -266,3 +930,547 @@ func TestColorOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestColorize(t *testing.T) {
+	t.Cleanup(func() { colorEnabled = true })
+
+	colorEnabled = true
+	if got, want := colorize(colorRed, "failed"), colorRed+"failed"+colorReset; got != want {
+		t.Errorf("colorize() with colors = %q, want %q", got, want)
+	}
+	colorEnabled = false
+	if got := colorize(colorRed, "failed"); got != "failed" {
+		t.Errorf("colorize() without colors = %q, want %q", got, "failed")
+	}
+}
+
+func TestShouldColor(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if shouldColor(&bytes.Buffer{}) {
+		t.Error("shouldColor() = true for a buffer")
+	}
+	if shouldColor(w) {
+		t.Error("shouldColor() = true for a pipe")
+	}
+
+	// NO_COLOR disables colors whatever its value, even an empty one
+	t.Setenv("NO_COLOR", "")
+	if shouldColor(os.Stdout) {
+		t.Error("shouldColor() = true with NO_COLOR set")
+	}
+}
+
+func TestNoColorOutput(t *testing.T) {
+	readStderr := captureStderr(t)
+	original := out
+	var b bytes.Buffer
+	out = &b
+	colorEnabled = false
+	t.Cleanup(func() {
+		out = original
+		colorEnabled = true
+	})
+
+	printError("failed")
+	printSuccess("done")
+	if got := readStderr() + b.String(); got != "Error: failed\ndone\n" {
+		t.Errorf("uncolored output = %q, want %q", got, "Error: failed\ndone\n")
+	}
+}
+
+func TestProgressOutputFollowsOut(t *testing.T) {
+	readStderr := captureStderr(t)
+	original := out
+	var b bytes.Buffer
+	out = &b
+	t.Cleanup(func() {
+		out = original
+	})
+
+	printSuccess("Creating tag")
+	printWarning("Tag exists")
+	if want := colorGreen + "Creating tag" + colorReset + "\n"; b.String() != want {
+		t.Errorf("progress output = %q, want %q", b.String(), want)
+	}
+	if got, want := readStderr(), colorYellow+"Warning: Tag exists"+colorReset+"\n"; got != want {
+		t.Errorf("warning output = %q, want %q", got, want)
+	}
+}
+
+func TestMessagePrefixes(t *testing.T) {
+	readStderr := captureStderr(t)
+	original := out
+	var b bytes.Buffer
+	out = &b
+	savedError, savedWarning, savedSuccess := errorPrefix, warningPrefix, successPrefix
+	errorPrefix, warningPrefix, successPrefix = "✗ ", "⚠ ", "✓ "
+	t.Cleanup(func() {
+		out = original
+		errorPrefix, warningPrefix, successPrefix = savedError, savedWarning, savedSuccess
+	})
+
+	printError("failed")
+	printWarning("careful")
+	printSuccess("done")
+	want := colorRed + "✗ failed" + colorReset + "\n" +
+		colorYellow + "⚠ careful" + colorReset + "\n"
+	if got := readStderr(); got != want {
+		t.Errorf("prefixed diagnostics = %q, want %q", got, want)
+	}
+	if want := colorGreen + "✓ done" + colorReset + "\n"; b.String() != want {
+		t.Errorf("prefixed output = %q, want %q", b.String(), want)
+	}
+}
+
+func TestCheckNoOverwrite(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "v1.0.0")
+
+	if err := checkNoOverwrite("v1.1.0"); err != nil {
+		t.Errorf("checkNoOverwrite() for a new tag error = %v, want nil", err)
+	}
+	err := checkNoOverwrite("v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("checkNoOverwrite() for an existing tag error = %v, want already exists", err)
+	}
+}
+
+func TestCreateTagTagger(t *testing.T) {
+	initTestRepo(t)
+
+	opts := tagOptions{taggerName: "Release Bot", taggerEmail: "release@example.com"}
+	if err := createTag("v1.0.0", "Release v1.0.0", opts); err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	got := gitCmd(t, "for-each-ref", "--format=%(taggername) %(taggeremail)", "refs/tags/v1.0.0")
+	if want := "Release Bot <release@example.com>"; got != want {
+		t.Errorf("tagger = %q, want %q", got, want)
+	}
+}
+
+func TestCheckTagMove(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "-a", "v1.0.0", "-m", "Release v1.0.0")
+	first := gitCmd(t, "rev-parse", "HEAD")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "second")
+
+	if moved, err := checkTagMove("v1.0.0", first, false); err != nil || moved {
+		t.Errorf("checkTagMove() at the same commit = %v, %v, want false, nil", moved, err)
+	}
+
+	_, err := checkTagMove("v1.0.0", "HEAD", false)
+	if err == nil || !strings.Contains(err.Error(), "--force-move") {
+		t.Errorf("checkTagMove() to a new commit error = %v, want a hint to --force-move", err)
+	}
+
+	if moved, err := checkTagMove("v1.0.0", "HEAD", true); err != nil || !moved {
+		t.Errorf("checkTagMove() with forceMove = %v, %v, want true, nil", moved, err)
+	}
+}
+
+func TestSigningError(t *testing.T) {
+	base := errors.New("exit status 128")
+	tests := []struct {
+		name      string
+		stderr    string
+		wantSetup bool
+	}{
+		{"missing key", "error: gpg failed to sign the data\nerror: unable to sign the tag", true},
+		{"no gpg program", "error: cannot run gpg: No such file or directory", true},
+		{"unknown key id", "gpg: skipped \"ABC\": No secret key", true},
+		{"other failure", "fatal: tag 'v1.0.0' already exists", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signingError(fmt.Errorf("%w: %s", base, tt.stderr))
+			if got := strings.Contains(err.Error(), "user.signingkey"); got != tt.wantSetup {
+				t.Errorf("signingError() = %v, want setup hint %v", err, tt.wantSetup)
+			}
+			if !strings.Contains(err.Error(), tt.stderr) {
+				t.Errorf("signingError() = %v, want git's message kept", err)
+			}
+		})
+	}
+}
+
+func TestCreateSignedTagWithoutKey(t *testing.T) {
+	initTestRepo(t)
+	t.Setenv("GNUPGHOME", t.TempDir())
+
+	err := createTag("v1.0.0", "Release v1.0.0", tagOptions{sign: true, keyID: "missing@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "user.signingkey") {
+		t.Errorf("createTag() with an unknown key error = %v, want the signing setup explained", err)
+	}
+	if tagExists("v1.0.0") {
+		t.Error("createTag() left a tag behind after signing failed")
+	}
+}
+
+func TestTagArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts tagOptions
+		want []string
+	}{
+		{"annotated", tagOptions{}, []string{"tag", "-a", "v1.0.0", "-m", "msg"}},
+		{"at commit", tagOptions{commit: "abc123"}, []string{"tag", "-a", "v1.0.0", "-m", "msg", "abc123"}},
+		{"signed", tagOptions{sign: true}, []string{"tag", "-s", "v1.0.0", "-m", "msg"}},
+		{"signed with key", tagOptions{sign: true, keyID: "ABCD"}, []string{"tag", "-u", "ABCD", "v1.0.0", "-m", "msg"}},
+		{"lightweight", tagOptions{lightweight: true}, []string{"tag", "v1.0.0"}},
+		{"lightweight at commit", tagOptions{lightweight: true, commit: "abc123"}, []string{"tag", "v1.0.0", "abc123"}},
+		{"ssh key", tagOptions{sign: true, signFormat: "ssh", sshKey: "/home/me/.ssh/id_ed25519"},
+			[]string{"-c", "gpg.format=ssh", "-c", "user.signingkey=/home/me/.ssh/id_ed25519", "tag", "-s", "v1.0.0", "-m", "msg"}},
+		{"x509", tagOptions{sign: true, signFormat: "x509"}, []string{"-c", "gpg.format=x509", "tag", "-s", "v1.0.0", "-m", "msg"}},
+		{"format without signing", tagOptions{signFormat: "ssh", sshKey: "key"}, []string{"tag", "-a", "v1.0.0", "-m", "msg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagArgs("v1.0.0", "msg", tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tagArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSSHKey(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("key"), perm); err != nil {
+			t.Fatal(err)
+		}
+		// WriteFile is subject to the umask
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantWarning bool
+		wantErr     bool
+	}{
+		{"private key", write("id_ed25519", 0o600), false, false},
+		{"world-readable private key", write("id_open", 0o644), true, false},
+		{"group-readable private key", write("id_group", 0o640), true, false},
+		{"public key", write("id_ed25519.pub", 0o644), false, false},
+		{"missing", filepath.Join(dir, "missing"), false, true},
+		{"directory", dir, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warning, err := checkSSHKey(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSSHKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (warning != "") != tt.wantWarning {
+				t.Errorf("checkSSHKey() warning = %q, wantWarning %v", warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"git", "tag", "-d", "v1.0.0"}, "git tag -d v1.0.0"},
+		{[]string{"git", "tag", "-a", "v1.0.0", "-m", "## [v1.0.0]\n- Fix"}, "git tag -a v1.0.0 -m '## [v1.0.0]\n- Fix'"},
+		{[]string{"git", "tag", "-m", "it's"}, `git tag -m 'it'\''s'`},
+		{[]string{"git", "tag", "-m", ""}, "git tag -m ''"},
+	}
+
+	for _, tt := range tests {
+		if got := formatCommand(tt.args...); got != tt.want {
+			t.Errorf("formatCommand(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCreateTagRunsGit(t *testing.T) {
+	var got [][]string
+	fakeGit(t, func(stdin string, args []string) (string, error) {
+		got = append(got, args)
+		return "", nil
+	})
+
+	opts := tagOptions{commit: "abc123", sign: true, keyID: "ABCD"}
+	if err := createTag("v1.0.0", "msg", opts); err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	if want := [][]string{tagArgs("v1.0.0", "msg", opts)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("createTag() ran git %q, want %q", got, want)
+	}
+}
+
+func TestTagEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		opts tagOptions
+		want []string
+	}{
+		{"default identity", tagOptions{}, nil},
+		{"name only", tagOptions{taggerName: "Release Bot"}, []string{"GIT_COMMITTER_NAME=Release Bot"}},
+		{"name and email", tagOptions{taggerName: "Release Bot", taggerEmail: "bot@example.com"},
+			[]string{"GIT_COMMITTER_NAME=Release Bot", "GIT_COMMITTER_EMAIL=bot@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagEnv(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tagEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateTagLightweight(t *testing.T) {
+	initTestRepo(t)
+
+	if err := createTag("nightly", "ignored", tagOptions{lightweight: true}); err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	if got := gitCmd(t, "cat-file", "-t", "refs/tags/nightly"); got != "commit" {
+		t.Errorf("nightly is a %s object, want a lightweight tag pointing at a commit", got)
+	}
+}
+
+func TestPushTag(t *testing.T) {
+	initTestRepo(t)
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	gitCmd(t, "init", "-q", "--bare", remoteDir)
+	gitCmd(t, "remote", "add", "upstream", remoteDir)
+
+	if err := createTag("v1.0.0", "Release v1.0.0", tagOptions{}); err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	if err := pushTag("upstream", "v1.0.0"); err != nil {
+		t.Fatalf("pushTag() error = %v", err)
+	}
+	if got := gitCmd(t, "ls-remote", "--tags", "upstream", "v1.0.0"); !strings.HasSuffix(got, "refs/tags/v1.0.0") {
+		t.Errorf("remote tags = %q, want v1.0.0 pushed", got)
+	}
+
+	// A failed push reports git's stderr and keeps the local tag
+	err := pushTag("missing", "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("pushTag() to an unknown remote error = %v, want git's message", err)
+	}
+	if !tagExists("v1.0.0") {
+		t.Error("local tag removed after a failed push")
+	}
+}
+
+func TestChangelogPushGate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte("# Changelog\n\n## [v1.0.0]\n- First release\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	builder := messageBuilder{changelogFile: path, extract: extractOptions{headingLevel: defaultHeadingLevel}}
+
+	tests := []struct {
+		name         string
+		tag          string
+		requireEntry bool
+		wantPush     bool
+	}{
+		{"entry found", "v1.0.0", true, true},
+		{"fallback message", "v1.1.0", true, false},
+		{"fallback without the gate", "v1.1.0", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, found, err := builder.build(tt.tag, "HEAD")
+			if err != nil {
+				t.Fatalf("build() error = %v", err)
+			}
+			warning := changelogPushGate(tt.tag, tt.requireEntry, found)
+			if (warning == "") != tt.wantPush {
+				t.Errorf("changelogPushGate(%q, %v, %v) = %q, want push %v", tt.tag, tt.requireEntry, found, warning, tt.wantPush)
+			}
+			if warning != "" && !strings.Contains(warning, tt.tag) {
+				t.Errorf("changelogPushGate() warning %q does not name the tag", warning)
+			}
+		})
+	}
+}
+
+func TestPushFollowTags(t *testing.T) {
+	initTestRepo(t)
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	gitCmd(t, "init", "-q", "--bare", remoteDir)
+	gitCmd(t, "remote", "add", "upstream", remoteDir)
+
+	// Without an upstream there is nothing to follow
+	err := checkHasUpstream()
+	if err == nil || !strings.Contains(err.Error(), "no upstream") {
+		t.Errorf("checkHasUpstream() without upstream error = %v, want no upstream", err)
+	}
+
+	gitCmd(t, "push", "-q", "-u", "upstream", "main")
+	if err := checkHasUpstream(); err != nil {
+		t.Errorf("checkHasUpstream() error = %v", err)
+	}
+
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "Fix crash")
+	if err := createTag("v1.0.0", "Release v1.0.0", tagOptions{}); err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	if err := pushFollowTags("upstream"); err != nil {
+		t.Fatalf("pushFollowTags() error = %v", err)
+	}
+	if got := gitCmd(t, "ls-remote", "--tags", "upstream", "v1.0.0"); !strings.HasSuffix(got, "refs/tags/v1.0.0") {
+		t.Errorf("remote tags = %q, want v1.0.0 pushed", got)
+	}
+	if local, remote := gitCmd(t, "rev-parse", "HEAD"), gitCmd(t, "rev-parse", "upstream/main"); local != remote {
+		t.Errorf("upstream/main = %s, want the pushed HEAD %s", remote, local)
+	}
+}
+
+func TestWriteMessageOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dist", "notes", "v1.0.0.md")
+	if err := writeMessageOutput(path, "## [v1.0.0]\n- First release"); err != nil {
+		t.Fatalf("writeMessageOutput() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("writeMessageOutput() did not create the file: %v", err)
+	}
+	if want := "## [v1.0.0]\n- First release\n"; string(data) != want {
+		t.Errorf("written message = %q, want %q", data, want)
+	}
+
+	// Without a path nothing is written
+	if err := writeMessageOutput("", "unused"); err != nil {
+		t.Errorf("writeMessageOutput() without a path error = %v", err)
+	}
+}
+
+func TestSuggestTag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	content := "# Changelog\n\n## [1.0.3] - 2025-08-27\n- Fix\n\n## [1.0.1] - 2025-08-26\n- Initial\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+	opts := extractOptions{headingLevel: defaultHeadingLevel}
+
+	tests := []struct {
+		name string
+		tag  string
+		want string
+	}{
+		{"existing entry is kept", "v1.0.3", "v1.0.3"},
+		{"non-interactive only warns", "v1.0.4", "v1.0.4"},
+		{"no close version", "v2.0.0", "v2.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestTag(tt.tag, path, opts, false); got != tt.want {
+				t.Errorf("suggestTag(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestTagInteractive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte("# Changelog\n\n## [1.0.3] - 2025-08-27\n- Fix\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test changelog: %v", err)
+	}
+	// The answers come through a pipe standing in for the terminal
+	originalIsTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return true }
+	t.Cleanup(func() { stdinIsTerminal = originalIsTerminal })
+
+	for _, tt := range []struct {
+		tag    string
+		prefix string
+		answer string
+		want   string
+	}{
+		{"v1.0.4", "", "y\n", "v1.0.3"},
+		{"v1.0.4", "", "n\n", "v1.0.4"},
+		{"app-v1.0.4", "app-", "y\n", "app-v1.0.3"},
+	} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("Failed to create pipe: %v", err)
+		}
+		_, _ = w.WriteString(tt.answer)
+		_ = w.Close()
+		originalStdin, originalOut := os.Stdin, out
+		os.Stdin, out = r, &bytes.Buffer{}
+
+		opts := extractOptions{headingLevel: defaultHeadingLevel, prefix: tt.prefix}
+		got := suggestTag(tt.tag, path, opts, true)
+		os.Stdin, out = originalStdin, originalOut
+		_ = r.Close()
+
+		if got != tt.want {
+			t.Errorf("suggestTag(%q) answering %q = %q, want %q", tt.tag, tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmWithoutTerminal(t *testing.T) {
+	// A piped "y" must not count as an answer
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	_, _ = w.WriteString("y\n")
+	_ = w.Close()
+	originalStdin, originalOut := os.Stdin, out
+	os.Stdin, out = r, &bytes.Buffer{}
+	t.Cleanup(func() {
+		os.Stdin, out = originalStdin, originalOut
+		_ = r.Close()
+	})
+
+	ok, err := confirm("Create 2 tag(s)?")
+	if ok || err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("confirm() without a terminal = %v, %v; want false and an error naming --force", ok, err)
+	}
+}
+
+func TestCheckUpToDate(t *testing.T) {
+	initTestRepo(t)
+	if err := checkUpToDate(true); err == nil || !strings.Contains(err.Error(), "no upstream") {
+		t.Errorf("checkUpToDate(true) without upstream error = %v, want no upstream", err)
+	}
+
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	gitCmd(t, "init", "-q", "--bare", remoteDir)
+	gitCmd(t, "remote", "add", "origin", remoteDir)
+	gitCmd(t, "push", "-q", "-u", "origin", "main")
+	if err := checkUpToDate(true); err != nil {
+		t.Errorf("checkUpToDate(true) in sync error = %v", err)
+	}
+
+	// Another clone pushes two commits that this checkout has not fetched
+	otherDir := filepath.Join(t.TempDir(), "other")
+	gitCmd(t, "clone", "-q", "-b", "main", remoteDir, otherDir)
+	for _, msg := range []string{"one", "two"} {
+		gitCmd(t, "-C", otherDir, "commit", "-q", "--allow-empty", "-m", msg)
+	}
+	gitCmd(t, "-C", otherDir, "push", "-q", "origin", "main")
+
+	err := checkUpToDate(true)
+	if err == nil || !strings.Contains(err.Error(), "2 commit(s) behind 'origin/main'") {
+		t.Errorf("checkUpToDate(true) behind error = %v, want 2 commits behind origin/main", err)
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// MessageParts are the sources of a tag message. buildMessage assembles
+// them in field order; every part is optional.
+type MessageParts struct {
+	// Subject is the first line of the message
+	Subject string
+	// Prepend is text placed before the changelog body
+	Prepend string
+	// Body is the changelog entry, or the rendered --template
+	Body string
+	// Append is text placed after the body, such as the diffstat
+	Append string
+	// Footer is the rendered --footer-template
+	Footer string
+	// Trailers are "Key: value" lines added after everything else
+	Trailers []string
+}
+
+// buildMessage assembles parts in the order subject, prepend, body, append,
+// footer, trailers. Present parts are separated by a blank line and empty
+// ones are skipped. Trailers join a footer that ends in a trailer block,
+// and trailers that are already present are not repeated.
+func buildMessage(parts MessageParts) string {
+	var message string
+	for _, part := range []string{parts.Subject, parts.Prepend, parts.Body, parts.Append, parts.Footer} {
+		part = strings.Trim(part, "\n")
+		if message == "" {
+			if strings.TrimSpace(part) != "" {
+				message = part
+			}
+			continue
+		}
+		message = appendParagraph(message, part)
+	}
+	// Without any text the trailers form the whole message
+	trailersOnly := message == ""
+	for _, trailer := range parts.Trailers {
+		switch {
+		case trailer == "":
+		case message == "":
+			message = trailer
+		case trailersOnly:
+			if !contains(strings.Split(message, "\n"), trailer) {
+				message += "\n" + trailer
+			}
+		default:
+			message = appendTrailer(message, trailer)
+		}
+	}
+	return message
+}
+
+// truncateSubject shortens the first line of message to at most max
+// characters, ending it with an ellipsis, and keeps the full line as the
+// first paragraph of the body. It reports whether the line was shortened;
+// a max of zero or less leaves message unchanged.
+func truncateSubject(message string, max int) (string, bool) {
+	first, rest, _ := strings.Cut(message, "\n")
+	if max <= 0 || utf8.RuneCountInString(first) <= max {
+		return message, false
+	}
+	runes := []rune(first)
+	subject := strings.TrimRight(string(runes[:max-1]), " \t") + "…"
+	body := first
+	if rest = strings.TrimLeft(rest, "\n"); rest != "" {
+		body += "\n" + rest
+	}
+	return subject + "\n\n" + body, true
+}
+
+// exitChangelogMissing is the exit status when --require-changelog finds no
+// entry for the tag, distinct from the general failure status 1, the flag
+// parsing status 2 and exitVerifyFailed
+const exitChangelogMissing = 4
+
+// errChangelogMissing is returned by build when the changelog has no entry
+// for the tag and one is required
+var errChangelogMissing = errors.New("no CHANGELOG entry")
+
+// failureStatus returns the exit status for err: exitChangelogMissing for a
+// missing required entry, and 1 otherwise
+func failureStatus(err error) int {
+	if errors.Is(err, errChangelogMissing) {
+		return exitChangelogMissing
+	}
+	return 1
+}
+
+// messageBuilder assembles tag messages from the changelog
+type messageBuilder struct {
+	// message is the --message or --message-file text, used instead of the
+	// changelog entry if set
+	message       string
+	changelogFile string
+	// moreChangelogs are searched for the entry too, after changelogFile
+	moreChangelogs []string
+	// fromUnreleased uses the Unreleased section when the changelog has no
+	// entry for the tag, under a release header dated releaseDate
+	fromUnreleased bool
+	releaseDate    string
+	// fromGitLog lists the commits since the previous tag in the fallback
+	// message used when the changelog has no entry
+	fromGitLog bool
+	// conventional builds the fallback message from the Conventional Commits
+	// since the previous tag, grouped into subsections under a release
+	// header dated releaseDate
+	conventional bool
+	// requireChangelog makes a missing entry an errChangelogMissing error
+	// instead of using the fallback message
+	requireChangelog bool
+	// stripHeading drops the version header line from the entry
+	stripHeading bool
+	// appendMessages are paragraphs added after the entry, or the rendered
+	// --template
+	appendMessages []string
+	// sectionVersion is the changelog version to extract instead of the
+	// tag name, if set
+	sectionVersion string
+	extract        extractOptions
+	forbidMarkers  []string
+	// allowedSections are the subsection names the entry may use, if set
+	allowedSections []string
+	// warnUnknownSections reports subsections outside allowedSections as a
+	// warning instead of an error
+	warnUnknownSections bool
+	// minBullets is the fewest list items the entry may have
+	minBullets int
+	// expectChecksum is the SHA-256 the extracted entry must have, if set
+	expectChecksum    string
+	messageTemplate   *template.Template
+	footerTemplate    *template.Template
+	appendDiffstat    bool
+	normalizeTrailers bool
+	// subjectMax is the longest first line of the message, if set
+	subjectMax int
+	// stampTrailer records the gtauto version, if set
+	stampTrailer string
+	// signoffTrailer is appended after all other trailers, if set
+	signoffTrailer string
+}
+
+// build returns the tag message for tagName at commit and reports whether a
+// changelog entry was found; otherwise the message is the given message or
+// a generic fallback
+func (b messageBuilder) build(tagName, commit string) (string, bool, error) {
+	var message string
+	var found bool
+	var err error
+	if b.message != "" {
+		printSuccess("Using the given tag message: skipping CHANGELOG extraction")
+		message = b.message
+	} else if message, found, err = b.entry(tagName, commit); err != nil {
+		return "", found, err
+	}
+
+	var data templateData
+	if b.messageTemplate != nil || b.footerTemplate != nil {
+		data, err = newTemplateData(tagName, commit, message)
+		if err != nil {
+			return "", found, fmt.Errorf("failed to collect template data: %w", err)
+		}
+	}
+	if b.messageTemplate != nil {
+		message, err = renderTemplate(b.messageTemplate, data)
+		if err != nil {
+			return "", found, fmt.Errorf("failed to render template: %w", err)
+		}
+	}
+
+	parts := MessageParts{Body: appendParagraphs(message, b.appendMessages)}
+
+	// The diffstat goes before the footer so trailers in the footer stay last
+	if b.appendDiffstat {
+		parts.Append, err = releaseDiffstat(tagName, commit)
+		if err != nil {
+			return "", found, fmt.Errorf("failed to compute diffstat: %w", err)
+		}
+	}
+
+	if b.footerTemplate != nil {
+		parts.Footer, err = renderTemplate(b.footerTemplate, data)
+		if err != nil {
+			return "", found, fmt.Errorf("failed to render footer template: %w", err)
+		}
+	}
+
+	parts.Trailers = []string{b.stampTrailer, b.signoffTrailer}
+	message = buildMessage(parts)
+
+	var truncated bool
+	if message, truncated = truncateSubject(message, b.subjectMax); truncated {
+		printWarning(fmt.Sprintf("Subject line is longer than %d characters, shortened with the full line kept in the body", b.subjectMax))
+	}
+
+	// Normalization sees the assembled message, trailers included
+	if b.normalizeTrailers {
+		supported, err := gitVersionAtLeast(trailersMinGitMajor, trailersMinGitMinor)
+		switch {
+		case err != nil:
+			printWarning(fmt.Sprintf("Could not determine git version, skipping trailer normalization: %v", err))
+		case !supported:
+			printWarning(fmt.Sprintf("git %d.%d or later is required to normalize trailers, skipping", trailersMinGitMajor, trailersMinGitMinor))
+		default:
+			message, err = normalizeTrailers(message)
+			if err != nil {
+				return "", found, fmt.Errorf("failed to normalize trailers: %w", err)
+			}
+		}
+	}
+
+	return message, found, nil
+}
+
+// entry returns the changelog entry for tagName, or the fallback message if
+// there is none, after the checks on the entry, and reports whether it was
+// found
+func (b messageBuilder) entry(tagName, commit string) (string, bool, error) {
+	version := tagName
+	if b.sectionVersion != "" {
+		version = b.sectionVersion
+	}
+	printSuccess(fmt.Sprintf("Extracting CHANGELOG entry for '%s'...", version))
+
+	message, err := b.extractEntry(version)
+	if errors.Is(err, errScanLimit) {
+		return "", false, err
+	}
+	found := err == nil
+	if !found && b.fromUnreleased {
+		var body string
+		if body, err = extractUnreleasedEntry(b.changelogFile, b.extract); err == nil {
+			printSuccess(fmt.Sprintf("No CHANGELOG entry for '%s', using the [Unreleased] section", version))
+			message = releaseHeader(b.extract, tagName, b.releaseDate) + "\n" + body
+			found = true
+		} else {
+			printWarning(fmt.Sprintf("Cannot use the [Unreleased] section: %v", err))
+		}
+	} else if found {
+		printSuccess("Found CHANGELOG entry")
+	}
+	if !found && b.requireChangelog {
+		return "", found, fmt.Errorf("%w for '%s' in %s (--require-changelog)", errChangelogMissing, version, b.changelogFile)
+	}
+	if !found {
+		printWarning(fmt.Sprintf("Could not find CHANGELOG entry for '%s'", version))
+		message = fmt.Sprintf("Release %s", tagName)
+		switch {
+		case b.conventional:
+			entry, err := releaseConventionalEntry(b.extract, tagName, commit, b.releaseDate)
+			if err != nil {
+				return "", found, fmt.Errorf("failed to list commits: %w", err)
+			}
+			if entry != "" {
+				printSuccess("Using the conventional commits since the previous tag as the tag message")
+				message = entry
+			} else {
+				printWarning("No feat, fix, perf or breaking change commits since the previous tag")
+			}
+		case b.fromGitLog:
+			log, err := releaseGitLog(tagName, commit)
+			if err != nil {
+				return "", found, fmt.Errorf("failed to list commits: %w", err)
+			}
+			if log != "" {
+				printSuccess("Using the commits since the previous tag as the tag message")
+				message = appendParagraph(message, log)
+			}
+		}
+	}
+
+	if b.expectChecksum != "" {
+		if !found {
+			return "", found, fmt.Errorf("no CHANGELOG entry for '%s' to verify the checksum of", version)
+		}
+		if actual := entryChecksum(message); !strings.EqualFold(actual, b.expectChecksum) {
+			return "", found, fmt.Errorf("CHANGELOG entry checksum mismatch for '%s': expected %s, got %s", version, b.expectChecksum, actual)
+		}
+		printSuccess("CHANGELOG entry checksum verified")
+	}
+
+	if b.minBullets > 0 {
+		if count := countBullets(message); !found || count < b.minBullets {
+			return "", found, fmt.Errorf("CHANGELOG entry for '%s' has %d bullet point(s), at least %d required", version, count, b.minBullets)
+		}
+	}
+
+	if found && len(b.forbidMarkers) > 0 {
+		if offending := findMarkers(message, b.forbidMarkers); len(offending) > 0 {
+			return "", found, fmt.Errorf("CHANGELOG entry for '%s' contains forbidden markers:\n  %s", version, strings.Join(offending, "\n  "))
+		}
+	}
+
+	if found && len(b.allowedSections) > 0 {
+		if unknown := unknownSubsections(message, b.allowedSections, b.extract.level()); len(unknown) > 0 {
+			problem := fmt.Sprintf("CHANGELOG entry for '%s' has subsections that are not allowed: %s (allowed: %s)", version, strings.Join(unknown, ", "), strings.Join(b.allowedSections, ", "))
+			if !b.warnUnknownSections {
+				return "", found, errors.New(problem)
+			}
+			printWarning(problem)
+		}
+	}
+
+	// The checks above see the entry as written in the changelog
+	if found && b.stripHeading {
+		message = stripHeading(message)
+	}
+	return message, found, nil
+}
+
+// changelogSeparator joins the entries found in several changelogs. A rule
+// rather than a blank line keeps them apart from the paragraphs inside an
+// entry.
+const changelogSeparator = "\n\n---\n\n"
+
+// extractEntry returns the entries for version in changelogFile and each of
+// moreChangelogs, in that order, joined with changelogSeparator. It fails
+// only if none of the files has an entry.
+func (b messageBuilder) extractEntry(version string) (string, error) {
+	var entries []string
+	var missing error
+	for _, file := range append([]string{b.changelogFile}, b.moreChangelogs...) {
+		entry, err := extractChangelogEntry(version, file, b.extract)
+		switch {
+		case errors.Is(err, errScanLimit):
+			return "", err
+		case err != nil:
+			if missing == nil {
+				missing = err
+			}
+		default:
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return "", missing
+	}
+	return strings.Join(entries, changelogSeparator), nil
+}
+
+// hasEntry reports whether any of the changelogs has an entry for version
+func (b messageBuilder) hasEntry(version string) bool {
+	_, err := b.extractEntry(version)
+	return err == nil
+}
+
+// releaseDiffstat summarizes the changes from the previous semver tag to
+// commit, or from the start of history if there is no previous tag. It
+// returns "" if nothing changed.
+func releaseDiffstat(tagName, commit string) (string, error) {
+	tags, err := listAllTags()
+	if err != nil {
+		return "", err
+	}
+	previous := previousSemverTag(tagName, tags)
+	stat, err := diffShortstat(previous, commit)
+	if err != nil || stat == "" {
+		return "", err
+	}
+	if previous == "" {
+		return stat + " since the first commit", nil
+	}
+	return fmt.Sprintf("%s since %s", stat, previous), nil
+}
+
+// releaseGitLog lists the subjects of the commits from the previous semver
+// tag to commit as "- subject" lines, or of the whole history if there is no
+// previous tag. It returns "" if there are no commits.
+func releaseGitLog(tagName, commit string) (string, error) {
+	tags, err := listAllTags()
+	if err != nil {
+		return "", err
+	}
+	subjects, err := commitsSinceLastTag(previousSemverTag(tagName, tags), commit)
+	if err != nil || len(subjects) == 0 {
+		return "", err
+	}
+	return "- " + strings.Join(subjects, "\n- "), nil
+}
+
+// releaseConventionalEntry renders the Conventional Commits from the previous
+// semver tag to commit, or of the whole history if there is no previous tag,
+// as a changelog entry for tagName dated date. It returns "" if none of the
+// commits has a type that is rendered.
+func releaseConventionalEntry(opts extractOptions, tagName, commit, date string) (string, error) {
+	tags, err := listAllTags()
+	if err != nil {
+		return "", err
+	}
+	messages, err := commitMessagesSince(previousSemverTag(tagName, tags), commit)
+	if err != nil {
+		return "", err
+	}
+	return renderConventionalEntry(releaseHeader(opts, tagName, date), parseConventionalCommits(messages), opts), nil
+}
+
+// tagMessageUnchanged reports whether the existing annotated tag tagName
+// already carries message, after git's usual message cleanup
+func tagMessageUnchanged(tagName, message string) (bool, error) {
+	info, err := tagInfo(tagName)
+	if err != nil {
+		return false, err
+	}
+	if !info.Annotated {
+		return false, nil
+	}
+	cleaned, err := cleanupMessage(message)
+	if err != nil {
+		return false, err
+	}
+	return cleaned == info.Message, nil
+}
+
+// readMessageFile reads a tag message from path, or from stdin if path is
+// "-". A single trailing newline is dropped; an empty message is an error.
+func readMessageFile(path string, stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	message := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if strings.TrimSpace(message) == "" {
+		if path == "-" {
+			return "", errors.New("the message on stdin is empty")
+		}
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return message, nil
+}
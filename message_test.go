@@ -0,0 +1,560 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTagMessageUnchanged(t *testing.T) {
+	initTestRepo(t)
+	message := "## [v1.0.0] - 2025-08-26\n\n### Added\n- First release"
+	if err := createTag("v1.0.0", message, tagOptions{}); err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	gitCmd(t, "tag", "light")
+
+	tests := []struct {
+		name    string
+		tag     string
+		message string
+		want    bool
+	}{
+		{"same message", "v1.0.0", message, true},
+		{"same after cleanup", "v1.0.0", message + "\n\n\n", true},
+		{"different message", "v1.0.0", message + "\n- Another change", false},
+		{"lightweight tag", "light", "initial", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tagMessageUnchanged(tt.tag, tt.message)
+			if err != nil {
+				t.Fatalf("tagMessageUnchanged() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("tagMessageUnchanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseDiffstat(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		gitCmd(t, "add", name)
+		gitCmd(t, "commit", "-q", "-m", "update "+name)
+	}
+
+	writeFile("a.txt", "one\ntwo\n")
+	got, err := releaseDiffstat("v1.0.0", "HEAD")
+	if err != nil {
+		t.Fatalf("releaseDiffstat() error = %v", err)
+	}
+	if want := "1 file changed, 2 insertions(+) since the first commit"; got != want {
+		t.Errorf("releaseDiffstat() without previous tag = %q, want %q", got, want)
+	}
+
+	gitCmd(t, "tag", "v1.0.0")
+	writeFile("a.txt", "one\n")
+	writeFile("b.txt", "three\n")
+	got, err = releaseDiffstat("v1.1.0", "HEAD")
+	if err != nil {
+		t.Fatalf("releaseDiffstat() error = %v", err)
+	}
+	if want := "2 files changed, 1 insertion(+), 1 deletion(-) since v1.0.0"; got != want {
+		t.Errorf("releaseDiffstat() = %q, want %q", got, want)
+	}
+
+	gitCmd(t, "tag", "v1.1.0")
+	got, err = releaseDiffstat("v1.2.0", "HEAD")
+	if err != nil {
+		t.Fatalf("releaseDiffstat() error = %v", err)
+	}
+	if got != "" {
+		t.Errorf("releaseDiffstat() with no changes = %q, want empty", got)
+	}
+}
+
+func TestBuildExpectChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte("# Changelog\n\n## [v1.0.0]\n- First release\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+	actual := entryChecksum("## [v1.0.0]\n- First release")
+
+	tests := []struct {
+		name     string
+		tag      string
+		checksum string
+		wantErr  []string
+	}{
+		{"matching checksum", "v1.0.0", actual, nil},
+		{"matching uppercase checksum", "v1.0.0", strings.ToUpper(actual), nil},
+		{"mismatch reports both", "v1.0.0", "deadbeef", []string{"expected deadbeef", "got " + actual}},
+		{"missing entry", "v2.0.0", actual, []string{"no CHANGELOG entry"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := messageBuilder{
+				changelogFile:  path,
+				extract:        extractOptions{headingLevel: defaultHeadingLevel},
+				expectChecksum: tt.checksum,
+			}
+			_, _, err := builder.build(tt.tag, "HEAD")
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("build() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("build() expected error, got nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("build() error = %q, want containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildAllowedSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	content := "# Changelog\n\n## [v1.0.0]\n\n### Added\n- Feature\n\n### Misc\n- Chore\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		warn    bool
+		wantErr bool
+	}{
+		{"not enforced", nil, false, false},
+		{"all allowed", []string{"Added", "Misc"}, false, false},
+		{"unknown subsection", []string{"Added", "Fixed"}, false, true},
+		{"warning only", []string{"Added", "Fixed"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := messageBuilder{
+				changelogFile:       path,
+				extract:             extractOptions{headingLevel: defaultHeadingLevel},
+				allowedSections:     tt.allowed,
+				warnUnknownSections: tt.warn,
+			}
+			_, _, err := builder.build("v1.0.0", "HEAD")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "Misc") {
+				t.Errorf("build() error = %q, want it to list Misc", err)
+			}
+		})
+	}
+}
+
+func TestBuildFromUnreleased(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	content := "# Changelog\n\n## [Unreleased]\n\n- Upcoming fix\n\n## [v1.0.0]\n- First release\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		tag            string
+		fromUnreleased bool
+		want           string
+		wantFound      bool
+	}{
+		{"own section wins", "v1.0.0", true, "## [v1.0.0]\n- First release", true},
+		{"unreleased under the new header", "v1.1.0", true, "## [v1.1.0] - 2025-09-01\n\n- Upcoming fix", true},
+		{"fallback without the flag", "v1.1.0", false, "Release v1.1.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := messageBuilder{
+				changelogFile:  path,
+				extract:        extractOptions{headingLevel: defaultHeadingLevel},
+				fromUnreleased: tt.fromUnreleased,
+				releaseDate:    "2025-09-01",
+			}
+			got, found, err := builder.build(tt.tag, "HEAD")
+			if err != nil {
+				t.Fatalf("build() error = %v", err)
+			}
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("build() = %q, %v, want %q, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestBuildFromGitLog(t *testing.T) {
+	initTestRepo(t)
+	gitCmd(t, "tag", "v1.0.0")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "Fix crash")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "Add flag")
+
+	tests := []struct {
+		name       string
+		tag        string
+		commit     string
+		fromGitLog bool
+		want       string
+	}{
+		{"commits since the previous tag", "v1.1.0", "HEAD", true, "Release v1.1.0\n\n- Add flag\n- Fix crash"},
+		{"whole history for the first tag", "v1.0.0", "v1.0.0", true, "Release v1.0.0\n\n- initial"},
+		{"fallback without the flag", "v1.1.0", "HEAD", false, "Release v1.1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := messageBuilder{
+				changelogFile: filepath.Join(t.TempDir(), "CHANGELOG.md"),
+				extract:       extractOptions{headingLevel: defaultHeadingLevel},
+				fromGitLog:    tt.fromGitLog,
+			}
+			got, found, err := builder.build(tt.tag, tt.commit)
+			if err != nil {
+				t.Fatalf("build() error = %v", err)
+			}
+			if got != tt.want || found {
+				t.Errorf("build() = %q, %v, want %q, false", got, found, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRequireChangelog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte("# Changelog\n\n## [v1.0.0]\n- First release\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+	builder := messageBuilder{
+		changelogFile:    path,
+		extract:          extractOptions{headingLevel: defaultHeadingLevel},
+		requireChangelog: true,
+	}
+
+	if got, found, err := builder.build("v1.0.0", "HEAD"); err != nil || !found || got != "## [v1.0.0]\n- First release" {
+		t.Errorf("build() of an existing entry = %q, %v, %v", got, found, err)
+	}
+
+	_, found, err := builder.build("v1.1.0", "HEAD")
+	if !errors.Is(err, errChangelogMissing) || found {
+		t.Fatalf("build() of a missing entry = %v, %v, want errChangelogMissing", found, err)
+	}
+	if status := failureStatus(err); status != exitChangelogMissing {
+		t.Errorf("failureStatus() = %d, want %d", status, exitChangelogMissing)
+	}
+	if status := failureStatus(errors.New("failed to render template")); status != 1 {
+		t.Errorf("failureStatus() of another error = %d, want 1", status)
+	}
+}
+
+func TestBuildMultipleChangelogs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write changelog: %v", err)
+		}
+		return path
+	}
+	primary := write("CHANGELOG.md", "# Changelog\n\n## [v1.1.0]\n- Feature\n\n## [v1.0.0]\n- First release\n")
+	security := write("CHANGELOG-security.md", "# Security\n\n## [v1.1.0]\n- Fixed CVE-2025-0001\n\n## [v0.9.0]\n- Fixed CVE-2024-0001\n")
+
+	tests := []struct {
+		name      string
+		tag       string
+		want      string
+		wantFound bool
+	}{
+		{"entries of both files", "v1.1.0", "## [v1.1.0]\n- Feature\n\n---\n\n## [v1.1.0]\n- Fixed CVE-2025-0001", true},
+		{"only the first file", "v1.0.0", "## [v1.0.0]\n- First release", true},
+		{"only a later file", "v0.9.0", "## [v0.9.0]\n- Fixed CVE-2024-0001", true},
+		{"in none of the files", "v2.0.0", "Release v2.0.0", false},
+	}
+
+	builder := messageBuilder{
+		changelogFile:  primary,
+		moreChangelogs: []string{security},
+		extract:        extractOptions{headingLevel: defaultHeadingLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := builder.build(tt.tag, "HEAD")
+			if err != nil {
+				t.Fatalf("build() error = %v", err)
+			}
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("build() = %q, %v, want %q, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+
+	builder.requireChangelog = true
+	if _, _, err := builder.build("v2.0.0", "HEAD"); !errors.Is(err, errChangelogMissing) {
+		t.Errorf("build() with --require-changelog error = %v, want errChangelogMissing", err)
+	}
+}
+
+func TestBuildTrailers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte("# Changelog\n\n## [v1.0.0]\n- First release\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+
+	builder := messageBuilder{
+		changelogFile:  path,
+		extract:        extractOptions{headingLevel: defaultHeadingLevel},
+		stampTrailer:   "Generated-by: gtauto 1.2.3",
+		signoffTrailer: "Signed-off-by: Jane Doe <jane@example.com>",
+	}
+	got, _, err := builder.build("v1.0.0", "HEAD")
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+	want := "## [v1.0.0]\n- First release\n\nGenerated-by: gtauto 1.2.3\nSigned-off-by: Jane Doe <jane@example.com>"
+	if got != want {
+		t.Errorf("build() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildAppendMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte("# Changelog\n\n## [v1.0.0]\n- First release\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write changelog: %v", err)
+	}
+
+	builder := messageBuilder{
+		changelogFile:  path,
+		extract:        extractOptions{headingLevel: defaultHeadingLevel},
+		appendMessages: []string{"Signed releases: https://example.com/keys", "", "See the docs"},
+		signoffTrailer: "Signed-off-by: Jane Doe <jane@example.com>",
+	}
+	got, _, err := builder.build("v1.0.0", "HEAD")
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+	want := "## [v1.0.0]\n- First release\n\nSigned releases: https://example.com/keys\n\nSee the docs\n\nSigned-off-by: Jane Doe <jane@example.com>"
+	if got != want {
+		t.Errorf("build() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildGivenMessage(t *testing.T) {
+	initTestRepo(t)
+	tmplPath := filepath.Join(t.TempDir(), "message.tmpl")
+	if err := os.WriteFile(tmplPath, []byte("{{.Tag}}: {{.Changelog}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseTemplateFile(tmplPath, templateDelims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		builder messageBuilder
+		want    string
+	}{
+		{
+			name:    "trailers",
+			builder: messageBuilder{stampTrailer: "Generated-by: gtauto 1.2.3", signoffTrailer: "Signed-off-by: Jane Doe <jane@example.com>"},
+			want:    "Hotfix\n\nGenerated-by: gtauto 1.2.3\nSigned-off-by: Jane Doe <jane@example.com>",
+		},
+		{
+			name:    "template",
+			builder: messageBuilder{messageTemplate: tmpl, appendMessages: []string{"See the docs"}},
+			want:    "v1.0.1: Hotfix\n\nSee the docs",
+		},
+		{
+			name:    "subject max",
+			builder: messageBuilder{subjectMax: 4},
+			want:    "Hot…\n\nHotfix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No CHANGELOG file exists; the given message replaces the entry
+			tt.builder.message = "Hotfix"
+			tt.builder.changelogFile = "CHANGELOG.md"
+			got, found, err := tt.builder.build("v1.0.1", "HEAD")
+			if err != nil {
+				t.Fatalf("build() error = %v", err)
+			}
+			if got != tt.want || found {
+				t.Errorf("build() = %q, %v, want %q, false", got, found, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildMessageFileSignoff covers --message-file - --signoff: the message
+// read from stdin still gets the trailer
+func TestBuildMessageFileSignoff(t *testing.T) {
+	message, err := readMessageFile("-", strings.NewReader("Release notes\n\n- Fix\n"))
+	if err != nil {
+		t.Fatalf("readMessageFile() error = %v", err)
+	}
+	builder := messageBuilder{
+		message:        message,
+		changelogFile:  filepath.Join(t.TempDir(), "CHANGELOG.md"),
+		signoffTrailer: "Signed-off-by: Jane Doe <jane@example.com>",
+	}
+	got, _, err := builder.build("v1.2.0", "HEAD")
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+	if want := "Release notes\n\n- Fix\n\nSigned-off-by: Jane Doe <jane@example.com>"; got != want {
+		t.Errorf("build() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts MessageParts
+		want  string
+	}{
+		{"empty", MessageParts{}, ""},
+		{"body only", MessageParts{Body: "## [v1.0.0]\n- Fix\n"}, "## [v1.0.0]\n- Fix"},
+		{
+			name: "all parts",
+			parts: MessageParts{
+				Subject:  "Release v1.0.0",
+				Prepend:  "Highlights first.",
+				Body:     "## [v1.0.0]\n- Fix",
+				Append:   "3 files changed since v0.9.0",
+				Footer:   "Downloads at https://example.com",
+				Trailers: []string{"Signed-off-by: Jane <jane@example.com>"},
+			},
+			want: "Release v1.0.0\n\nHighlights first.\n\n## [v1.0.0]\n- Fix\n\n3 files changed since v0.9.0\n\nDownloads at https://example.com\n\nSigned-off-by: Jane <jane@example.com>",
+		},
+		{"blank parts skipped", MessageParts{Subject: "  ", Prepend: "\n\n", Body: "Body", Footer: "\n"}, "Body"},
+		{"trailers join a trailer footer", MessageParts{Body: "Body", Footer: "Refs: #12", Trailers: []string{"Generated-by: gtauto 1.0.0"}}, "Body\n\nRefs: #12\nGenerated-by: gtauto 1.0.0"},
+		{"duplicate trailer", MessageParts{Body: "Body", Trailers: []string{"Refs: #1", "Refs: #1", ""}}, "Body\n\nRefs: #1"},
+		{"trailers only", MessageParts{Trailers: []string{"Refs: #1", "Refs: #2", "Refs: #1"}}, "Refs: #1\nRefs: #2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildMessage(tt.parts); got != tt.want {
+				t.Errorf("buildMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildMessageCombinations checks every combination of present and
+// absent parts: present parts appear once, in pipeline order, separated by
+// exactly one blank line
+func TestBuildMessageCombinations(t *testing.T) {
+	names := []string{"Subject", "Prepend", "Body", "Append", "Footer", "Trailers"}
+	values := []string{"subject line", "prepended", "changelog body", "appended", "footer text", "Signed-off-by: Jane <jane@example.com>"}
+
+	for mask := 0; mask < 1<<len(names); mask++ {
+		var parts MessageParts
+		var want []string
+		fields := []*string{&parts.Subject, &parts.Prepend, &parts.Body, &parts.Append, &parts.Footer}
+		for i := range names {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			want = append(want, values[i])
+			if i < len(fields) {
+				*fields[i] = values[i]
+			} else {
+				parts.Trailers = []string{values[i]}
+			}
+		}
+
+		if got := buildMessage(parts); got != strings.Join(want, "\n\n") {
+			t.Errorf("buildMessage(%+v) = %q, want %q", parts, got, strings.Join(want, "\n\n"))
+		}
+	}
+}
+
+func TestTruncateSubject(t *testing.T) {
+	long := "Add support for signing tags with a configured GPG key"
+	tests := []struct {
+		name          string
+		message       string
+		max           int
+		want          string
+		wantTruncated bool
+	}{
+		{"short subject", "Fix crash\n\n- Details", 50, "Fix crash\n\n- Details", false},
+		{"exactly max", "12345", 5, "12345", false},
+		{"long subject moves to body", long + "\n\n- Details", 20, "Add support for sig…\n\n" + long + "\n- Details", true},
+		{"subject only", long, 20, "Add support for sig…\n\n" + long, true},
+		{"trailing space trimmed", "Release notes for v1.2.0", 9, "Release…\n\nRelease notes for v1.2.0", true},
+		{"counts characters, not bytes", "Änderungen für Version 1.2.0", 10, "Änderunge…\n\nÄnderungen für Version 1.2.0", true},
+		{"disabled", long, 0, long, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncateSubject(tt.message, tt.max)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("truncateSubject() = %q, %v, want %q, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestReadMessageFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{"file", writeFile("notes.md", "Release notes\n\n- Fix\n"), "", "Release notes\n\n- Fix", false},
+		{"only one newline trimmed", writeFile("blank.md", "Notes\n\n"), "", "Notes\n", false},
+		{"indentation kept", writeFile("code.md", "  indented\n"), "", "  indented", false},
+		{"stdin", "-", "From a pipe\n", "From a pipe", false},
+		{"empty file", writeFile("empty.md", ""), "", "", true},
+		{"blank stdin", "-", "\n", "", true},
+		{"missing file", filepath.Join(dir, "missing.md"), "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMessageFile(tt.path, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readMessageFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readMessageFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
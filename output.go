@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runResult is the --json summary of a run
+type runResult struct {
+	Tag            string   `json:"tag,omitempty"`
+	Created        bool     `json:"created"`
+	Message        string   `json:"message,omitempty"`
+	ChangelogFound bool     `json:"changelog_found"`
+	Warnings       []string `json:"warnings,omitempty"`
+	// Timings are the --timing phases in milliseconds, with the total
+	Timings map[string]float64 `json:"timings,omitempty"`
+}
+
+// reporter shows the progress of a run. printSuccess, printWarning and
+// printError go through report, so every message reaches both the human
+// and the --json output.
+type reporter interface {
+	success(message string)
+	warning(message string)
+	error(message string)
+	// finish reports the outcome of a run that did not fail
+	finish(result runResult)
+}
+
+// report is the reporter of the current run
+var report reporter = textReporter{}
+
+// textReporter prints success messages to out, and warnings and errors to
+// stderr so that stdout keeps only what a pipe expects. Messages are colored
+// unless disabled.
+type textReporter struct{}
+
+func (textReporter) success(message string) {
+	fmt.Fprintln(out, colorize(colorGreen, successPrefix+message))
+}
+
+func (textReporter) warning(message string) {
+	fmt.Fprintln(os.Stderr, colorize(colorYellow, warningPrefix+message))
+}
+
+func (textReporter) error(message string) {
+	fmt.Fprintln(os.Stderr, colorize(colorRed, errorPrefix+message))
+}
+
+func (textReporter) finish(runResult) {}
+
+// quietReporter drops success and warning messages for --quiet and prints
+// errors to stderr
+type quietReporter struct{}
+
+func (quietReporter) success(string) {}
+
+func (quietReporter) warning(string) {}
+
+func (quietReporter) error(message string) {
+	fmt.Fprintln(os.Stderr, colorize(colorRed, errorPrefix+message))
+}
+
+func (quietReporter) finish(runResult) {}
+
+// jsonReporter writes a single JSON object to w for --json: the result of
+// the run, or the first error. Success messages are dropped and warnings
+// are collected into the object.
+type jsonReporter struct {
+	w        io.Writer
+	warnings []string
+	written  bool
+}
+
+func (r *jsonReporter) success(string) {}
+
+func (r *jsonReporter) warning(message string) {
+	r.warnings = append(r.warnings, message)
+}
+
+func (r *jsonReporter) error(message string) {
+	r.write(struct {
+		Error    string   `json:"error"`
+		Warnings []string `json:"warnings,omitempty"`
+	}{message, r.warnings})
+}
+
+func (r *jsonReporter) finish(result runResult) {
+	result.Warnings = r.warnings
+	r.write(result)
+}
+
+// write encodes v unless an object was already written
+func (r *jsonReporter) write(v interface{}) {
+	if r.written {
+		return
+	}
+	r.written = true
+	encoder := json.NewEncoder(r.w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
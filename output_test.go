@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// useJSONReporter sends progress messages to a jsonReporter writing to the
+// returned buffer for the duration of the test
+func useJSONReporter(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var b bytes.Buffer
+	previous := report
+	report = &jsonReporter{w: &b}
+	t.Cleanup(func() { report = previous })
+	return &b
+}
+
+func decodeObject(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var object map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&object); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, data)
+	}
+	if decoder.More() {
+		t.Fatalf("output has more than one JSON value:\n%s", data)
+	}
+	return object
+}
+
+func TestJSONReporterResult(t *testing.T) {
+	b := useJSONReporter(t)
+
+	printSuccess("Creating tag 'v1.2.0'...")
+	printWarning("Could not find CHANGELOG entry for 'v1.2.0'")
+	report.finish(runResult{Tag: "v1.2.0", Created: true, Message: "Release v1.2.0"})
+	// Nothing is written after the result
+	printError("late error")
+
+	want := map[string]interface{}{
+		"tag":             "v1.2.0",
+		"created":         true,
+		"message":         "Release v1.2.0",
+		"changelog_found": false,
+		"warnings":        []interface{}{"Could not find CHANGELOG entry for 'v1.2.0'"},
+	}
+	if got := decodeObject(t, b.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("result = %v, want %v", got, want)
+	}
+}
+
+func TestJSONReporterError(t *testing.T) {
+	b := useJSONReporter(t)
+
+	printWarning("Tag 'v1.2.0' already exists")
+	printError("Failed to create tag: exit status 128")
+	report.finish(runResult{Tag: "v1.2.0"})
+
+	want := map[string]interface{}{
+		"error":    "Failed to create tag: exit status 128",
+		"warnings": []interface{}{"Tag 'v1.2.0' already exists"},
+	}
+	if got := decodeObject(t, b.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("error = %v, want %v", got, want)
+	}
+}
+
+// captureStderr redirects os.Stderr to a file for the rest of the test and
+// returns a function that reads what was written to it
+func captureStderr(t *testing.T) func() string {
+	t.Helper()
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Stderr
+	os.Stderr = stderr
+	t.Cleanup(func() { os.Stderr = previous })
+	return func() string {
+		data, err := os.ReadFile(stderr.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+func TestTextReporterStreams(t *testing.T) {
+	readStderr := captureStderr(t)
+	var stdout bytes.Buffer
+	previousReport, previousOut, previousColor := report, out, colorEnabled
+	report, out, colorEnabled = textReporter{}, &stdout, false
+	t.Cleanup(func() {
+		report, out, colorEnabled = previousReport, previousOut, previousColor
+	})
+
+	printSuccess("Tag 'v1.2.0' created successfully")
+	printWarning("Could not find CHANGELOG entry for 'v1.2.0'")
+	printError("Failed to push tag")
+
+	if want := "Tag 'v1.2.0' created successfully\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	want := warningPrefix + "Could not find CHANGELOG entry for 'v1.2.0'\n" + errorPrefix + "Failed to push tag\n"
+	if got := readStderr(); got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+}
+
+func TestQuietReporter(t *testing.T) {
+	readStderr := captureStderr(t)
+	var stdout bytes.Buffer
+	previousReport, previousOut, previousColor := report, out, colorEnabled
+	report, out, colorEnabled = quietReporter{}, &stdout, false
+	t.Cleanup(func() {
+		report, out, colorEnabled = previousReport, previousOut, previousColor
+	})
+
+	printSuccess("✓ Tag 'v1.2.0' created successfully")
+	printWarning("Could not find CHANGELOG entry for 'v1.2.0'")
+	printError("Failed to push tag")
+	report.finish(runResult{Tag: "v1.2.0", Created: true})
+
+	if stdout.Len() != 0 {
+		t.Errorf("quiet output on stdout = %q, want none", stdout.String())
+	}
+	if got, want := readStderr(), errorPrefix+"Failed to push tag\n"; got != want {
+		t.Errorf("quiet output on stderr = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// defaultPager is used when $PAGER is unset or empty
+const defaultPager = "less -R"
+
+// pagerCommand returns the pager command line from $PAGER, or defaultPager
+func pagerCommand() []string {
+	if fields := strings.Fields(os.Getenv("PAGER")); len(fields) > 0 {
+		return fields
+	}
+	return strings.Fields(defaultPager)
+}
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// page shows text through the pager on w. It reports false without showing
+// anything when w is not a terminal or no pager is available, so the caller
+// can print text directly instead.
+func page(w io.Writer, text string) bool {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return false
+	}
+	args := pagerCommand()
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return false
+	}
+
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = f
+	cmd.Stderr = os.Stderr
+	return cmd.Run() == nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPagerCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		pager string
+		want  []string
+	}{
+		{"unset uses default", "", []string{"less", "-R"}},
+		{"whitespace uses default", "  ", []string{"less", "-R"}},
+		{"custom pager", "more", []string{"more"}},
+		{"custom pager with args", "less -FRX", []string{"less", "-FRX"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PAGER", tt.pager)
+			if got := pagerCommand(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pagerCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageNonTerminal(t *testing.T) {
+	var b bytes.Buffer
+	if page(&b, "message") {
+		t.Error("page() to a buffer = true, want false")
+	}
+	if b.Len() != 0 {
+		t.Errorf("page() wrote %q to a non-terminal", b.String())
+	}
+}
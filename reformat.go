@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keepAChangelogSections are the Keep a Changelog subsections in canonical order
+var keepAChangelogSections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+var (
+	headingRegex = regexp.MustCompile(`^(#+)\s+(.*?)\s*$`)
+	bulletRegex  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+)
+
+// subsection is a "### Name" block of a changelog section
+type subsection struct {
+	name  string
+	lines []string
+}
+
+// reformatSection re-emits a changelog section in strict Keep a Changelog
+// form: the known subsections in canonical order, "-" bullets and a single
+// blank line between blocks. Subsections with the same name are merged and
+// empty ones dropped. Unknown subsections are kept after the known ones; a
+// warning is returned for each of them.
+func reformatSection(body string) (string, []string) {
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+
+	// The section header determines the subsection level
+	var header string
+	level := defaultHeadingLevel
+	if match := headingRegex.FindStringSubmatch(lines[0]); match != nil {
+		header = match[1] + " " + match[2]
+		level = len(match[1])
+		lines = lines[1:]
+	}
+	subsectionPrefix := strings.Repeat("#", level+1)
+
+	var preamble []string
+	var blocks []*subsection
+	byName := make(map[string]*subsection)
+	var current *subsection
+	for _, line := range lines {
+		if match := headingRegex.FindStringSubmatch(line); match != nil && match[1] == subsectionPrefix {
+			name := canonicalSectionName(match[2])
+			current = byName[strings.ToLower(name)]
+			if current == nil {
+				current = &subsection{name: name}
+				byName[strings.ToLower(name)] = current
+				blocks = append(blocks, current)
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		line = bulletRegex.ReplaceAllString(strings.TrimRight(line, " \t"), "$1- ")
+		if current == nil {
+			preamble = append(preamble, line)
+		} else {
+			current.lines = append(current.lines, line)
+		}
+	}
+
+	var ordered []*subsection
+	for _, name := range keepAChangelogSections {
+		if block := byName[strings.ToLower(name)]; block != nil {
+			ordered = append(ordered, block)
+		}
+	}
+	var warnings []string
+	for _, block := range blocks {
+		if !contains(keepAChangelogSections, block.name) {
+			ordered = append(ordered, block)
+			warnings = append(warnings, fmt.Sprintf("unknown subsection '%s' moved to the end", block.name))
+		}
+	}
+
+	var parts []string
+	if header != "" {
+		parts = append(parts, header)
+	}
+	if len(preamble) > 0 {
+		parts = append(parts, strings.Join(preamble, "\n"))
+	}
+	for _, block := range ordered {
+		if len(block.lines) == 0 {
+			continue
+		}
+		parts = append(parts, subsectionPrefix+" "+block.name+"\n"+strings.Join(block.lines, "\n"))
+	}
+	return strings.Join(parts, "\n\n"), warnings
+}
+
+// canonicalSectionName returns the Keep a Changelog spelling of name if it
+// is a known subsection, matched case-insensitively, and name otherwise
+func canonicalSectionName(name string) string {
+	for _, known := range keepAChangelogSections {
+		if strings.EqualFold(name, known) {
+			return known
+		}
+	}
+	return name
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReformatSection(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name: "canonical order and bullet style",
+			body: `## [v1.0.1] - 2025-08-27
+
+### Fixed
+* Bug fix 1
+
+### added
++ New feature A
+
+
+- New feature B
+  * Nested detail
+
+### Security
+`,
+			want: `## [v1.0.1] - 2025-08-27
+
+### Added
+- New feature A
+- New feature B
+  - Nested detail
+
+### Fixed
+- Bug fix 1`,
+			wantWarnings: nil,
+		},
+		{
+			name: "unknown subsections move to the end",
+			body: `## [v1.0.1] - 2025-08-27
+
+### Notes
+- Read the upgrade guide
+
+### Changed
+- Behaviour change
+
+### Changed
+- Another change`,
+			want: `## [v1.0.1] - 2025-08-27
+
+### Changed
+- Behaviour change
+- Another change
+
+### Notes
+- Read the upgrade guide`,
+			wantWarnings: []string{"unknown subsection 'Notes' moved to the end"},
+		},
+		{
+			name: "preamble and deeper heading level",
+			body: `### [v2.0.0]
+Major rewrite.
+
+#### Removed
+- Legacy API`,
+			want: `### [v2.0.0]
+
+Major rewrite.
+
+#### Removed
+- Legacy API`,
+			wantWarnings: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := reformatSection(tt.body)
+			if got != tt.want {
+				t.Errorf("reformatSection() mismatch\nGot:\n%s\n\nWant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("reformatSection() warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// retagSummary counts the outcome of runRetagAll for each tag
+type retagSummary struct {
+	Updated   int
+	Skipped   int
+	Unchanged int
+}
+
+// String renders the summary line printed at the end of retag-all
+func (s retagSummary) String() string {
+	return fmt.Sprintf("%d updated, %d skipped (no changelog entry), %d unchanged", s.Updated, s.Skipped, s.Unchanged)
+}
+
+// runRetagAll recreates every existing tag as an annotated tag whose message
+// is its changelogFile entry, on the commit it already points to. Tags
+// without an entry are skipped, and annotated tags that already carry the
+// entry are left alone. Rewriting tags needs force; dryRun only reports what
+// would change. With a component prefix, only the tags that start with it
+// are considered. A tag that cannot be recreated is pointed back at its old
+// tag object, keeping its tagger, date and signature, and the run stops
+// with an error.
+func runRetagAll(changelogFile string, extractOpts extractOptions, tagOpts tagOptions, force, dryRun bool) (retagSummary, error) {
+	var summary retagSummary
+	if !force && !dryRun {
+		return summary, fmt.Errorf("retag-all rewrites existing tags; use --force to confirm or --dry-run to preview")
+	}
+
+	tags, err := listAllTags()
+	if err != nil {
+		return summary, fmt.Errorf("failed to list tags: %v", err)
+	}
+
+	for _, tagName := range tags {
+		// Other components' tags belong to other changelogs
+		if !strings.HasPrefix(tagName, extractOpts.prefix) {
+			continue
+		}
+		entry, err := extractChangelogEntry(tagName, changelogFile, extractOpts)
+		if err != nil {
+			printWarning(fmt.Sprintf("Skipping '%s': %v", tagName, err))
+			summary.Skipped++
+			continue
+		}
+
+		unchanged, err := tagMessageUnchanged(tagName, entry)
+		if err != nil {
+			return summary, fmt.Errorf("failed to read tag '%s': %v", tagName, err)
+		}
+		if unchanged {
+			summary.Unchanged++
+			continue
+		}
+
+		info, err := tagInfo(tagName)
+		if err != nil {
+			return summary, fmt.Errorf("failed to read tag '%s': %v", tagName, err)
+		}
+		opts := tagOpts
+		opts.commit = info.Commit
+		if dryRun {
+			printSuccess("Would run: " + formatCommand(append([]string{"git"}, tagArgs(tagName, entry, opts)...)...))
+			summary.Updated++
+			continue
+		}
+
+		// The old tag object stays in the object database after the ref is
+		// deleted, so the ref can be pointed back at it
+		oldObject, err := runGit("rev-parse", "refs/tags/"+tagName)
+		if err != nil {
+			return summary, fmt.Errorf("failed to read tag '%s': %v", tagName, err)
+		}
+		if err := deleteTag(tagName); err != nil {
+			return summary, fmt.Errorf("failed to delete tag '%s': %v", tagName, err)
+		}
+		if err := createTag(tagName, entry, opts); err != nil {
+			if _, restoreErr := runGit("update-ref", "refs/tags/"+tagName, strings.TrimSpace(string(oldObject))); restoreErr != nil {
+				return summary, fmt.Errorf("failed to recreate tag '%s': %v; restoring it also failed: %v", tagName, err, restoreErr)
+			}
+			return summary, fmt.Errorf("failed to recreate tag '%s', restored it: %v", tagName, err)
+		}
+		printSuccess(fmt.Sprintf("✓ Updated tag '%s'", tagName))
+		summary.Updated++
+	}
+	return summary, nil
+}
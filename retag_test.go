@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunRetagAll(t *testing.T) {
+	initTestRepo(t)
+	changelog := "# Changelog\n\n## [1.1.0]\n\n- Second\n\n## [1.0.0]\n\n- First\n"
+	if err := os.WriteFile("CHANGELOG.md", []byte(changelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, "tag", "v1.0.0")
+	gitCmd(t, "commit", "-q", "--allow-empty", "-m", "second")
+	gitCmd(t, "tag", "-a", "v1.1.0", "-m", "## [1.1.0]\n\n- Second")
+	gitCmd(t, "tag", "nightly")
+	firstCommit := gitCmd(t, "rev-parse", "v1.0.0")
+
+	if _, err := runRetagAll("CHANGELOG.md", extractOptions{}, tagOptions{}, false, false); err == nil {
+		t.Error("runRetagAll() without --force returned nil error")
+	}
+
+	// A dry run changes nothing
+	summary, err := runRetagAll("CHANGELOG.md", extractOptions{}, tagOptions{}, false, true)
+	if err != nil {
+		t.Fatalf("runRetagAll() dry run error = %v", err)
+	}
+	if want := (retagSummary{Updated: 1, Skipped: 1, Unchanged: 1}); summary != want {
+		t.Errorf("runRetagAll() dry run = %+v, want %+v", summary, want)
+	}
+	if info, _ := tagInfo("v1.0.0"); info.Annotated {
+		t.Error("runRetagAll() dry run recreated the tag")
+	}
+
+	summary, err = runRetagAll("CHANGELOG.md", extractOptions{}, tagOptions{}, true, false)
+	if err != nil {
+		t.Fatalf("runRetagAll() error = %v", err)
+	}
+	if want := (retagSummary{Updated: 1, Skipped: 1, Unchanged: 1}); summary != want {
+		t.Errorf("runRetagAll() = %+v, want %+v", summary, want)
+	}
+	info, err := tagInfo("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Annotated || info.Commit != firstCommit || info.Message != "- First" {
+		t.Errorf("v1.0.0 after retag-all = %+v, want an annotated tag on %s with the changelog entry", info, firstCommit)
+	}
+	if info, _ := tagInfo("nightly"); info.Annotated {
+		t.Error("runRetagAll() recreated a tag without a changelog entry")
+	}
+
+	// Once retagged, every tag is unchanged
+	summary, err = runRetagAll("CHANGELOG.md", extractOptions{}, tagOptions{}, true, false)
+	if err != nil {
+		t.Fatalf("runRetagAll() second run error = %v", err)
+	}
+	if want := (retagSummary{Skipped: 1, Unchanged: 2}); summary != want {
+		t.Errorf("runRetagAll() second run = %+v, want %+v", summary, want)
+	}
+}
+
+func TestRunRetagAllRestoresTag(t *testing.T) {
+	initTestRepo(t)
+	t.Setenv("GNUPGHOME", t.TempDir())
+	changelog := "# Changelog\n\n## [1.0.0]\n\n- First\n"
+	if err := os.WriteFile("CHANGELOG.md", []byte(changelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, "tag", "-a", "v1.0.0", "-m", "Old notes")
+	oldObject := gitCmd(t, "rev-parse", "refs/tags/v1.0.0")
+
+	// Signing with a missing key fails after the old tag was deleted
+	_, err := runRetagAll("CHANGELOG.md", extractOptions{}, tagOptions{sign: true, keyID: "missing@example.com"}, true, false)
+	if err == nil || !strings.Contains(err.Error(), "restored it") {
+		t.Fatalf("runRetagAll() with a failing signature error = %v, want the tag restored", err)
+	}
+	// The same tag object is back, so its tagger, date and signature are kept
+	if got := gitCmd(t, "rev-parse", "refs/tags/v1.0.0"); got != oldObject {
+		t.Errorf("v1.0.0 points at %s after the failure, want the old tag object %s", got, oldObject)
+	}
+}
+
+func TestRunRetagAllPrefix(t *testing.T) {
+	initTestRepo(t)
+	changelog := "# Changelog\n\n## [1.0.0]\n\n- Frontend\n"
+	if err := os.WriteFile("CHANGELOG.md", []byte(changelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, "tag", "frontend-v1.0.0")
+	gitCmd(t, "tag", "v1.0.0")
+	gitCmd(t, "tag", "backend-v1.0.0")
+
+	summary, err := runRetagAll("CHANGELOG.md", extractOptions{prefix: "frontend-"}, tagOptions{}, true, false)
+	if err != nil {
+		t.Fatalf("runRetagAll() error = %v", err)
+	}
+	if want := (retagSummary{Updated: 1}); summary != want {
+		t.Errorf("runRetagAll() = %+v, want %+v", summary, want)
+	}
+	if info, _ := tagInfo("frontend-v1.0.0"); !info.Annotated || info.Message != "- Frontend" {
+		t.Errorf("frontend-v1.0.0 after retag-all = %+v, want an annotated tag with the changelog entry", info)
+	}
+	for _, tagName := range []string{"v1.0.0", "backend-v1.0.0"} {
+		if info, _ := tagInfo(tagName); info.Annotated {
+			t.Errorf("runRetagAll() with --prefix frontend- recreated %s", tagName)
+		}
+	}
+}
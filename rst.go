@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Changelog formats selected with --changelog-format
+const (
+	formatMarkdown = "markdown"
+	formatRST      = "rst"
+)
+
+// changelogFormat returns the format of changelogFile: format if it is set,
+// and otherwise rst for a .rst file and markdown for anything else
+func changelogFormat(format, changelogFile string) (string, error) {
+	switch format {
+	case formatMarkdown, formatRST:
+		return format, nil
+	case "":
+		if strings.EqualFold(filepath.Ext(changelogFile), ".rst") {
+			return formatRST, nil
+		}
+		return formatMarkdown, nil
+	}
+	return "", fmt.Errorf("--changelog-format must be markdown or rst, got %q", format)
+}
+
+// rstAdornmentChars are the punctuation characters that reStructuredText
+// section underlines and overlines are usually made of
+const rstAdornmentChars = "=-~^*+#\"'`:._"
+
+// rstVersionTitleRegex matches a section title that starts with a version,
+// such as "1.2.0 (2025-08-27)" or "v1.2.0"
+var rstVersionTitleRegex = regexp.MustCompile(`^[vV]?[0-9]+\.[0-9]+`)
+
+// isRSTAdornment reports whether line is a section underline or overline
+// such as "=======": at least three of the same adornment character
+func isRSTAdornment(line string) bool {
+	line = strings.TrimRight(line, " \t")
+	if len(line) < 3 || !strings.ContainsRune(rstAdornmentChars, rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// isRSTTitle reports whether lines[i] is a section title: a line of text
+// underlined by lines[i+1]
+func isRSTTitle(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.TrimSpace(lines[i]) != "" && !isRSTAdornment(lines[i]) && isRSTAdornment(lines[i+1])
+}
+
+// rstSectionTitleRegex matches a version section title, capturing the
+// version and the rest of the title, e.g. "1.2.0" and " (2025-08-27)"
+var rstSectionTitleRegex = regexp.MustCompile(`^([vV]?[0-9]+\.[0-9]+\S*)(.*)$`)
+
+// parseChangelogSectionsRST is parseChangelogSections for reStructuredText
+// changelogs; Line is the line of the section title
+func parseChangelogSectionsRST(changelogFile string) ([]changelogSection, error) {
+	file, err := os.Open(changelogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var sections []changelogSection
+	for i := range lines {
+		if !isRSTTitle(lines, i) {
+			continue
+		}
+		match := rstSectionTitleRegex.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if match == nil {
+			continue
+		}
+		sections = append(sections, changelogSection{
+			Version: match[1],
+			Date:    sectionDateRegex.FindString(match[2]),
+			Line:    i + 1,
+		})
+	}
+	return sections, nil
+}
+
+// extractChangelogEntryRST is extractChangelogEntry for reStructuredText
+// changelogs, whose version headers are section titles such as
+//
+//	1.2.0 (2025-08-27)
+//	==================
+//
+// The entry keeps its title and adornment lines and ends before the title of
+// the next version, whatever its adornment.
+func extractChangelogEntryRST(tagName, changelogFile string, opts extractOptions) (string, error) {
+	file, err := os.Open(changelogFile)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	version := strings.TrimPrefix(opts.version(tagName), "v")
+	versionRegex := regexp.MustCompile(fmt.Sprintf(`(?i)^v?%s%s`, versionMatchPattern(version), versionEndPattern))
+
+	maxLines := opts.maxLines
+	if maxLines == 0 {
+		maxLines = defaultMaxLines
+	}
+
+	// A section title is only known by the line after it, so read one line
+	// past the limit to recognize a title on the last line allowed
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for len(lines) <= maxLines && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	// sectionStart includes the overline of the title at i, if there is one
+	sectionStart := func(i int) int {
+		if i > 0 && isRSTAdornment(lines[i-1]) {
+			return i - 1
+		}
+		return i
+	}
+
+	start, end := -1, len(lines)
+	for i := 0; i < len(lines); i++ {
+		if i >= maxLines {
+			if start < 0 {
+				return "", fmt.Errorf("version %s not found in the first %d lines of the changelog (see --max-lines)", tagName, maxLines)
+			}
+			return "", fmt.Errorf("%w: scanned %d lines of %s without finding the end of the entry for %s; the file may be malformed (see --max-lines)", errScanLimit, maxLines, changelogFile, tagName)
+		}
+		if !isRSTTitle(lines, i) {
+			continue
+		}
+		title := strings.TrimSpace(lines[i])
+		if start < 0 {
+			if versionRegex.MatchString(title) {
+				start = sectionStart(i)
+				// Skip the underline
+				i++
+			}
+			continue
+		}
+		if rstVersionTitleRegex.MatchString(title) {
+			end = sectionStart(i)
+			break
+		}
+	}
+
+	if start < 0 {
+		return "", fmt.Errorf("version %s not found in changelog", tagName)
+	}
+	return strings.TrimRight(strings.Join(lines[start:end], "\n"), "\n"), nil
+}
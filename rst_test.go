@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const rstChangelog = `Changelog
+=========
+
+1.2.0 (2025-08-27)
+==================
+
+Features
+--------
+
+- Added rst support
+
+1.2.0-rc.1 (2025-08-20)
+=======================
+
+- Release candidate
+
+============
+v1.1.0
+============
+
+* Fixed a crash
+
+1.0.0
+-----
+
+- Initial release
+`
+
+func TestExtractChangelogEntryRST(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.rst")
+	if err := os.WriteFile(path, []byte(rstChangelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "subsections stay in the entry",
+			tag:  "v1.2.0",
+			want: "1.2.0 (2025-08-27)\n==================\n\nFeatures\n--------\n\n- Added rst support",
+		},
+		{
+			name: "ends before an overlined title",
+			tag:  "1.2.0-rc.1",
+			want: "1.2.0-rc.1 (2025-08-20)\n=======================\n\n- Release candidate",
+		},
+		{
+			name: "overline is kept",
+			tag:  "v1.1.0",
+			want: "============\nv1.1.0\n============\n\n* Fixed a crash",
+		},
+		{
+			name: "last entry with another underline",
+			tag:  "v1.0.0",
+			want: "1.0.0\n-----\n\n- Initial release",
+		},
+		{
+			name: "build metadata",
+			tag:  "v1.0.0+build.5",
+			want: "1.0.0\n-----\n\n- Initial release",
+		},
+		{
+			name:    "missing version",
+			tag:     "v2.0.0",
+			wantErr: true,
+		},
+		{
+			name:    "prefix of another version",
+			tag:     "v1.2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractChangelogEntry(tt.tag, path, extractOptions{format: formatRST})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractChangelogEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extractChangelogEntry() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, err := extractChangelogEntry("v1.2.0", path, extractOptions{format: formatRST, maxLines: 8})
+	if !errors.Is(err, errScanLimit) {
+		t.Errorf("extractChangelogEntry() past --max-lines error = %v, want errScanLimit", err)
+	}
+	_, err = extractChangelogEntry("v1.0.0", path, extractOptions{format: formatRST, maxLines: 8})
+	if err == nil || errors.Is(err, errScanLimit) || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("extractChangelogEntry() of a version past --max-lines error = %v, want it not found", err)
+	}
+}
+
+func TestChangelogFormat(t *testing.T) {
+	tests := []struct {
+		format, file string
+		want         string
+		wantErr      bool
+	}{
+		{"", "CHANGELOG.md", formatMarkdown, false},
+		{"", "docs/HISTORY.RST", formatRST, false},
+		{"", "CHANGELOG", formatMarkdown, false},
+		{"rst", "CHANGELOG.txt", formatRST, false},
+		{"markdown", "CHANGELOG.rst", formatMarkdown, false},
+		{"asciidoc", "CHANGELOG.adoc", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := changelogFormat(tt.format, tt.file)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("changelogFormat(%q, %q) = %q, %v, want %q", tt.format, tt.file, got, err, tt.want)
+		}
+	}
+}
+
+func TestStripHeadingRST(t *testing.T) {
+	tests := []struct {
+		entry, want string
+	}{
+		{"1.2.0 (2025-08-27)\n==================\n\n- Added rst support", "- Added rst support"},
+		{"======\nv1.1.0\n======\n\n* Fixed a crash", "* Fixed a crash"},
+		{"1.0.0\n-----", "1.0.0\n-----"},
+	}
+
+	for _, tt := range tests {
+		if got := stripHeading(tt.entry); got != tt.want {
+			t.Errorf("stripHeading(%q) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+	if got := stripHeading("Release notes\nwithout a title"); !strings.HasPrefix(got, "Release notes") {
+		t.Errorf("stripHeading() removed a line that is not a title: %q", got)
+	}
+}
+
+func TestParseChangelogSectionsRST(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.rst")
+	if err := os.WriteFile(path, []byte(rstChangelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseChangelogSections(path, extractOptions{format: formatRST})
+	if err != nil {
+		t.Fatalf("parseChangelogSections() error = %v", err)
+	}
+	want := []changelogSection{
+		{Version: "1.2.0", Date: "2025-08-27", Line: 4},
+		{Version: "1.2.0-rc.1", Date: "2025-08-20", Line: 12},
+		{Version: "v1.1.0", Line: 18},
+		{Version: "1.0.0", Line: 23},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChangelogSections() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed SemVer 2.0.0 version
+type semver struct {
+	Major, Minor, Patch int
+	// Prerelease is the dot-separated pre-release part without its "-", if any
+	Prerelease string
+	// Build is the build metadata without its "+", if any
+	Build string
+}
+
+// semverRegex is the SemVer 2.0.0 grammar with an optional "v" prefix
+var semverRegex = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// parseSemver parses a tag such as "v1.2.0-rc.1+build.5"
+func parseSemver(tag string) (semver, bool) {
+	match := semverRegex.FindStringSubmatch(tag)
+	if match == nil {
+		return semver{}, false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	return semver{Major: major, Minor: minor, Patch: patch, Prerelease: match[4], Build: match[5]}, true
+}
+
+// validateSemver checks that tagName, after an optional "v" prefix, is a
+// SemVer 2.0.0 version such as "v1.2.0-rc.1+build.5"
+func validateSemver(tagName string) error {
+	if _, ok := parseSemver(tagName); ok {
+		return nil
+	}
+	core := strings.TrimPrefix(tagName, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	if n := len(strings.Split(core, ".")); n != 3 {
+		return fmt.Errorf("tag '%s' is not a semantic version: expected MAJOR.MINOR.PATCH, got %d version number(s)", tagName, n)
+	}
+	return fmt.Errorf("tag '%s' is not a semantic version: expected MAJOR.MINOR.PATCH without leading zeros, optionally followed by -prerelease and +build", tagName)
+}
+
+// String formats v without a "v" prefix, e.g. "1.2.0-rc.1+build.5"
+func (v semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// compareSemver orders a and b by SemVer precedence, returning -1, 0 or 1.
+// Build metadata is ignored.
+func compareSemver(a, b semver) int {
+	for _, pair := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if pair[0] != pair[1] {
+			return compareInts(pair[0], pair[1])
+		}
+	}
+
+	// A pre-release has lower precedence than the release itself
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	}
+
+	aParts := strings.Split(a.Prerelease, ".")
+	bParts := strings.Split(b.Prerelease, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if c := comparePrereleaseIdentifiers(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aParts), len(bParts))
+}
+
+// comparePrereleaseIdentifiers compares numeric identifiers numerically and
+// others lexically; numeric identifiers sort before alphanumeric ones
+func comparePrereleaseIdentifiers(a, b string) int {
+	aNum, aErr := strconv.Atoi(a)
+	bNum, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(aNum, bNum)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// previousSemverTag returns the highest semver tag in tags that has lower
+// precedence than tagName, or "" if there is none. If tagName is not a
+// semver tag, the highest semver tag other than tagName is returned.
+func previousSemverTag(tagName string, tags []string) string {
+	current, currentOK := parseSemver(tagName)
+	var best string
+	var bestVersion semver
+	for _, tag := range tags {
+		version, ok := parseSemver(tag)
+		if !ok || tag == tagName {
+			continue
+		}
+		if currentOK && compareSemver(version, current) >= 0 {
+			continue
+		}
+		if best == "" || compareSemver(version, bestVersion) > 0 {
+			best, bestVersion = tag, version
+		}
+	}
+	return best
+}
+
+// sortTagsDescending sorts semver tags from highest to lowest precedence,
+// followed by all other tags in name order
+func sortTagsDescending(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, aOK := parseSemver(tags[i])
+		b, bOK := parseSemver(tags[j])
+		switch {
+		case aOK && bOK:
+			if c := compareSemver(a, b); c != 0 {
+				return c > 0
+			}
+			return tags[i] < tags[j]
+		case aOK != bOK:
+			return aOK
+		}
+		return tags[i] < tags[j]
+	})
+}
+
+// latestSemverTag returns the semver tag with the highest precedence, or ""
+// if there are no semver tags
+func latestSemverTag() (string, error) {
+	tags, err := listAllTags()
+	if err != nil {
+		return "", err
+	}
+	var semverTags []string
+	for _, tag := range tags {
+		if _, ok := parseSemver(tag); ok {
+			semverTags = append(semverTags, tag)
+		}
+	}
+	if len(semverTags) == 0 {
+		return "", nil
+	}
+	sortTagsDescending(semverTags)
+	return semverTags[0], nil
+}
+
+// bumpParts lists the supported --bump values
+var bumpParts = []string{"major", "minor", "patch"}
+
+// bumpVersion returns the release after current with part ("major",
+// "minor" or "patch") incremented and the lower parts reset, e.g. "v1.2.3"
+// becomes "v1.3.0" for "minor". A pre-release is replaced by its release
+// when that is already the requested bump, so "v2.0.0-rc.1" becomes
+// "v2.0.0" for "major". An empty current starts from v0.0.0. Build metadata
+// is dropped and the "v" prefix of current is kept.
+func bumpVersion(current, part string) (string, error) {
+	if !contains(bumpParts, part) {
+		return "", fmt.Errorf("invalid bump '%s' (available: %s)", part, strings.Join(bumpParts, ", "))
+	}
+
+	prefix := "v"
+	version := semver{}
+	if current != "" {
+		var ok bool
+		if version, ok = parseSemver(current); !ok {
+			return "", fmt.Errorf("'%s' is not a semantic version", current)
+		}
+		if !strings.HasPrefix(current, "v") {
+			prefix = ""
+		}
+	}
+	pre := version.Prerelease != ""
+	version.Prerelease, version.Build = "", ""
+
+	switch part {
+	case "major":
+		if !pre || version.Minor != 0 || version.Patch != 0 {
+			version.Major++
+		}
+		version.Minor, version.Patch = 0, 0
+	case "minor":
+		if !pre || version.Patch != 0 {
+			version.Minor++
+		}
+		version.Patch = 0
+	case "patch":
+		if !pre {
+			version.Patch++
+		}
+	}
+	return prefix + version.String(), nil
+}
+
+var prereleaseLabelRegex = regexp.MustCompile(`^[0-9A-Za-z-]*[A-Za-z-][0-9A-Za-z-]*$`)
+
+// bumpPrerelease returns the next label pre-release after current:
+// "v1.0.0-rc.1" becomes "v1.0.0-rc.2", "v1.0.0-beta.3" becomes
+// "v1.0.0-rc.1", and a release such as "v1.0.0" starts "v1.0.1-rc.1" so
+// that the pre-release sorts above it. Likewise a label that sorts below the
+// current one bumps the patch: "v1.0.0-rc.3" with alpha becomes
+// "v1.0.1-alpha.1". An empty current starts from v0.0.0.
+// Build metadata is dropped and the "v" prefix of current is kept.
+func bumpPrerelease(current, label string) (string, error) {
+	if !prereleaseLabelRegex.MatchString(label) {
+		return "", fmt.Errorf("invalid pre-release label '%s': use letters, digits and hyphens, not only digits", label)
+	}
+
+	prefix := "v"
+	version := semver{}
+	if current != "" {
+		var ok bool
+		if version, ok = parseSemver(current); !ok {
+			return "", fmt.Errorf("'%s' is not a semantic version", current)
+		}
+		if !strings.HasPrefix(current, "v") {
+			prefix = ""
+		}
+	}
+	version.Build = ""
+	original := version
+
+	parts := strings.Split(version.Prerelease, ".")
+	switch {
+	case version.Prerelease == "":
+		version.Patch++
+		version.Prerelease = label + ".1"
+	case parts[0] != label:
+		version.Prerelease = label + ".1"
+		if compareSemver(version, original) < 0 {
+			version.Patch++
+		}
+	default:
+		last := len(parts) - 1
+		if n, err := strconv.Atoi(parts[last]); err == nil && last > 0 {
+			parts[last] = strconv.Itoa(n + 1)
+		} else {
+			parts = append(parts, "1")
+		}
+		version.Prerelease = strings.Join(parts, ".")
+	}
+	return prefix + version.String(), nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		tag    string
+		want   semver
+		wantOK bool
+	}{
+		{"v1.2.3", semver{Major: 1, Minor: 2, Patch: 3}, true},
+		{"1.2.3", semver{Major: 1, Minor: 2, Patch: 3}, true},
+		{"v1.2.0-rc.1+build.5", semver{Major: 1, Minor: 2, Prerelease: "rc.1", Build: "build.5"}, true},
+		{"v1.0.0+exp.sha.5114f85", semver{Major: 1, Build: "exp.sha.5114f85"}, true},
+		{"v1.0", semver{}, false},
+		{"1.0.0.0", semver{}, false},
+		{"v01.0.0", semver{}, false},
+		{"v1.0.0-01", semver{}, false},
+		{"release", semver{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseSemver(tt.tag)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseSemver(%q) = %+v, %v, want %+v, %v", tt.tag, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestValidateSemver(t *testing.T) {
+	tests := []struct {
+		tag     string
+		wantErr string
+	}{
+		{"v1.2.0", ""},
+		{"1.2.0", ""},
+		{"v1.2.0-rc.1+build.5", ""},
+		{"v1.0", "got 2 version number(s)"},
+		{"1.0.0.0", "got 4 version number(s)"},
+		{"v1.0-rc.1", "got 2 version number(s)"},
+		{"release", "got 1 version number(s)"},
+		{"v01.0.0", "without leading zeros"},
+		{"v1.0.0-", "without leading zeros"},
+	}
+
+	for _, tt := range tests {
+		err := validateSemver(tt.tag)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("validateSemver(%q) error = %v", tt.tag, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("validateSemver(%q) error = %v, want it to contain %q", tt.tag, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	// Precedence example from the SemVer 2.0.0 specification, lowest first
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, _ := parseSemver(ordered[i])
+		b, _ := parseSemver(ordered[i+1])
+		if got := compareSemver(a, b); got != -1 {
+			t.Errorf("compareSemver(%s, %s) = %d, want -1", ordered[i], ordered[i+1], got)
+		}
+		if got := compareSemver(b, a); got != 1 {
+			t.Errorf("compareSemver(%s, %s) = %d, want 1", ordered[i+1], ordered[i], got)
+		}
+	}
+
+	a, _ := parseSemver("v1.0.0+build.1")
+	b, _ := parseSemver("1.0.0+build.2")
+	if got := compareSemver(a, b); got != 0 {
+		t.Errorf("compareSemver() ignoring build metadata = %d, want 0", got)
+	}
+}
+
+func TestPreviousSemverTag(t *testing.T) {
+	tags := []string{"v0.9.0", "v1.0.0", "v1.1.0-rc.1", "v1.1.0", "v2.0.0", "nightly"}
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"v1.1.0", "v1.1.0-rc.1"},
+		{"v1.2.0", "v1.1.0"},
+		{"v1.0.0", "v0.9.0"},
+		{"v0.1.0", ""},
+		{"nightly", "v2.0.0"},
+	}
+
+	for _, tt := range tests {
+		if got := previousSemverTag(tt.tag, tags); got != tt.want {
+			t.Errorf("previousSemverTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestSortTagsDescending(t *testing.T) {
+	tags := []string{"nightly", "v1.0.0", "v1.10.0", "v1.2.0", "v1.2.0-rc.1", "1.1.0", "archive"}
+	sortTagsDescending(tags)
+	want := []string{"v1.10.0", "v1.2.0", "v1.2.0-rc.1", "1.1.0", "v1.0.0", "archive", "nightly"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("sortTagsDescending() = %q, want %q", tags, want)
+	}
+}
+
+func TestBumpPrerelease(t *testing.T) {
+	tests := []struct {
+		current string
+		label   string
+		want    string
+		wantErr bool
+	}{
+		{"v1.0.0-rc.1", "rc", "v1.0.0-rc.2", false},
+		{"v1.0.0-rc.9", "rc", "v1.0.0-rc.10", false},
+		{"1.0.0-rc.1", "rc", "1.0.0-rc.2", false},
+		{"v1.0.0-rc", "rc", "v1.0.0-rc.1", false},
+		{"v1.0.0-rc.1.2", "rc", "v1.0.0-rc.1.3", false},
+		{"v1.0.0-rc.1.hotfix", "rc", "v1.0.0-rc.1.hotfix.1", false},
+		{"v1.0.0-beta.3", "rc", "v1.0.0-rc.1", false},
+		{"v1.0.0-rc.3", "alpha", "v1.0.1-alpha.1", false},
+		{"v1.0.0-rc.3", "beta", "v1.0.1-beta.1", false},
+		{"v1.0.0-rc.2+build.7", "rc", "v1.0.0-rc.3", false},
+		{"v1.0.0", "rc", "v1.0.1-rc.1", false},
+		{"", "alpha", "v0.0.1-alpha.1", false},
+		{"v1.0.0", "", "", true},
+		{"v1.0.0", "42", "", true},
+		{"v1.0.0", "rc.1", "", true},
+		{"release", "rc", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := bumpPrerelease(tt.current, tt.label)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("bumpPrerelease(%q, %q) error = %v, wantErr %v", tt.current, tt.label, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("bumpPrerelease(%q, %q) = %q, want %q", tt.current, tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	tests := []struct {
+		current string
+		part    string
+		want    string
+		wantErr bool
+	}{
+		{"v1.2.3", "major", "v2.0.0", false},
+		{"v1.2.3", "minor", "v1.3.0", false},
+		{"v1.2.3", "patch", "v1.2.4", false},
+		{"1.2.3", "patch", "1.2.4", false},
+		{"v1.2.3+build.5", "patch", "v1.2.4", false},
+		{"v2.0.0-rc.1", "major", "v2.0.0", false},
+		{"v2.1.0-rc.1", "major", "v3.0.0", false},
+		{"v1.3.0-rc.1", "minor", "v1.3.0", false},
+		{"v1.3.1-rc.1", "minor", "v1.4.0", false},
+		{"v1.2.4-rc.1", "patch", "v1.2.4", false},
+		{"", "patch", "v0.0.1", false},
+		{"", "minor", "v0.1.0", false},
+		{"", "major", "v1.0.0", false},
+		{"v1.2.3", "build", "", true},
+		{"release", "patch", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := bumpVersion(tt.current, tt.part)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("bumpVersion(%q, %q) error = %v, wantErr %v", tt.current, tt.part, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("bumpVersion(%q, %q) = %q, want %q", tt.current, tt.part, got, tt.want)
+		}
+	}
+}
+
+func TestLatestSemverTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{"no tags", nil, ""},
+		{"no semver tags", []string{"nightly"}, ""},
+		{"highest wins", []string{"v1.2.0", "v1.10.0-rc.1", "v1.9.0", "nightly"}, "v1.10.0-rc.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeGit(t, func(stdin string, args []string) (string, error) {
+				return strings.Join(tt.tags, "\n"), nil
+			})
+			got, err := latestSemverTag()
+			if err != nil {
+				t.Fatalf("latestSemverTag() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("latestSemverTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
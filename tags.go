@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"text/tabwriter"
+)
+
+// listTagsFormats lists the supported --format values for --list-tags;
+// checklist is the default of the list subcommand
+var listTagsFormats = []string{"text", "json", "checklist"}
+
+// tagListing describes a git tag and whether the changelog documents it
+type tagListing struct {
+	Name          string `json:"name"`
+	SectionExists bool   `json:"section_exists"`
+	Annotated     bool   `json:"annotated"`
+	Signed        bool   `json:"signed"`
+}
+
+// listTags returns every tag matching the glob filter (all tags if it is
+// empty), highest semver first, with its changelog and signature status.
+// With a component prefix in opts, only the tags that start with it are
+// listed, and the prefix is left out when looking for their section.
+func listTags(sections []changelogSection, opts extractOptions, filter string) ([]tagListing, error) {
+	tags, err := componentTags(opts)
+	if err != nil {
+		return nil, err
+	}
+	if filter != "" {
+		if _, err := path.Match(filter, ""); err != nil {
+			return nil, fmt.Errorf("invalid filter '%s': %w", filter, err)
+		}
+		var matching []string
+		for _, name := range tags {
+			if ok, _ := path.Match(filter, name); ok {
+				matching = append(matching, name)
+			}
+		}
+		tags = matching
+	}
+	// Sort by the versions after the component prefix, which all tags have
+	for i := range tags {
+		tags[i] = opts.version(tags[i])
+	}
+	sortTagsDescending(tags)
+	for i := range tags {
+		tags[i] = opts.prefix + tags[i]
+	}
+
+	versionSet := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		versionSet[section.Version] = true
+	}
+
+	infos, err := tagInfos(tags)
+	if err != nil {
+		return nil, err
+	}
+	listings := make([]tagListing, len(tags))
+	for i, name := range tags {
+		listings[i] = tagListing{Name: name, Annotated: infos[i].Annotated, Signed: infos[i].Signed}
+		for _, candidate := range tagCandidates(opts.version(name)) {
+			if versionSet[candidate] {
+				listings[i].SectionExists = true
+				break
+			}
+		}
+	}
+	return listings, nil
+}
+
+// writeTagListing writes the tag listings to w in the given format
+func writeTagListing(w io.Writer, listings []tagListing, format string) error {
+	switch format {
+	case "json":
+		if listings == nil {
+			listings = []tagListing{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listings)
+	case "text":
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "TAG\tSECTION\tTYPE\tSIGNED")
+		for _, listing := range listings {
+			kind := "lightweight"
+			if listing.Annotated {
+				kind = "annotated"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", listing.Name, yesNo(listing.SectionExists), kind, yesNo(listing.Signed))
+		}
+		return writer.Flush()
+	case "checklist":
+		for _, listing := range listings {
+			if listing.SectionExists {
+				fmt.Fprintf(w, "✓ %s\n", listing.Name)
+			} else {
+				fmt.Fprintf(w, "✗ %s (no CHANGELOG section)\n", listing.Name)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format '%s' for --list-tags (available: %s)", format, strings.Join(listTagsFormats, ", "))
+	}
+}
+
+// runListTags writes the tag listing for changelogFile to output
+func runListTags(changelogFile string, opts extractOptions, filter, format, output string) error {
+	if !contains(listTagsFormats, format) {
+		return fmt.Errorf("unknown format '%s' for --list-tags (available: %s)", format, strings.Join(listTagsFormats, ", "))
+	}
+
+	sections, err := parseChangelogSections(changelogFile, opts)
+	if err != nil {
+		return err
+	}
+	listings, err := listTags(sections, opts, filter)
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(output)
+	if err != nil {
+		return err
+	}
+	if err := writeTagListing(w, listings, format); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestListTags(t *testing.T) {
+	fakeAuditGit(t, []string{"v1.0.0", "1.1.0", "experiment", "v1.2.0"}, map[string]bool{"v1.2.0": true}, 0)
+	sections := []changelogSection{{Version: "v1.2.0"}, {Version: "v1.1.0"}}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   []tagListing
+	}{
+		{
+			name: "all tags",
+			want: []tagListing{
+				{Name: "v1.2.0", SectionExists: true, Annotated: true, Signed: true},
+				{Name: "1.1.0", SectionExists: true, Annotated: true},
+				{Name: "v1.0.0", Annotated: true},
+				{Name: "experiment", Annotated: true},
+			},
+		},
+		{
+			name:   "filtered",
+			filter: "v1.*",
+			want: []tagListing{
+				{Name: "v1.2.0", SectionExists: true, Annotated: true, Signed: true},
+				{Name: "v1.0.0", Annotated: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listTags(sections, extractOptions{}, tt.filter)
+			if err != nil {
+				t.Fatalf("listTags() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listTags() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := listTags(sections, extractOptions{}, "v1.["); err == nil {
+		t.Error("listTags() with a malformed glob expected error, got nil")
+	}
+}
+
+func TestListTagsPrefix(t *testing.T) {
+	fakeAuditGit(t, []string{"frontend-v1.0.0", "v1.0.0", "backend-v1.0.0", "frontend-v1.1.0"}, nil, 0)
+	sections := []changelogSection{{Version: "1.0.0"}}
+
+	got, err := listTags(sections, extractOptions{prefix: "frontend-"}, "")
+	if err != nil {
+		t.Fatalf("listTags() error = %v", err)
+	}
+	want := []tagListing{
+		{Name: "frontend-v1.1.0", Annotated: true},
+		{Name: "frontend-v1.0.0", SectionExists: true, Annotated: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listTags() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestWriteTagListing(t *testing.T) {
+	listings := []tagListing{
+		{Name: "v1.1.0", SectionExists: true, Annotated: true, Signed: true},
+		{Name: "v1.0.0"},
+	}
+
+	var b bytes.Buffer
+	if err := writeTagListing(&b, listings, "text"); err != nil {
+		t.Fatalf("writeTagListing() error = %v", err)
+	}
+	want := "TAG     SECTION  TYPE         SIGNED\n" +
+		"v1.1.0  yes      annotated    yes\n" +
+		"v1.0.0  no       lightweight  no\n"
+	if b.String() != want {
+		t.Errorf("writeTagListing() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := writeTagListing(&b, nil, "json"); err != nil {
+		t.Fatalf("writeTagListing() error = %v", err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("writeTagListing() with no tags = %q, want empty JSON array", b.String())
+	}
+
+	b.Reset()
+	if err := writeTagListing(&b, listings, "checklist"); err != nil {
+		t.Fatalf("writeTagListing() error = %v", err)
+	}
+	if want := "✓ v1.1.0\n✗ v1.0.0 (no CHANGELOG section)\n"; b.String() != want {
+		t.Errorf("writeTagListing() checklist =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	if err := writeTagListing(&b, listings, "csv"); err == nil {
+		t.Error("writeTagListing() with csv format expected error, got nil")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateData is the context available to message templates
+type templateData struct {
+	// Tag is the tag being created, e.g. "v1.0.0"
+	Tag string
+	// Version is the tag without its "v" prefix, e.g. "1.0.0"
+	Version string
+	// Changelog is the extracted changelog entry (or the fallback message)
+	Changelog string
+	// Commit is the short hash of the commit being tagged
+	Commit string
+	// Branch is the current branch, or "HEAD" when detached
+	Branch string
+	// Author is the author of the commit being tagged
+	Author string
+}
+
+// newTemplateData collects the git metadata of commit for the template
+// context, running each git command once
+func newTemplateData(tagName, commit, changelog string) (templateData, error) {
+	data := templateData{
+		Tag:       tagName,
+		Version:   strings.TrimPrefix(tagName, "v"),
+		Changelog: changelog,
+	}
+
+	for _, field := range []struct {
+		dest *string
+		args []string
+	}{
+		{&data.Commit, []string{"rev-parse", "--short", commit}},
+		{&data.Branch, []string{"rev-parse", "--abbrev-ref", "HEAD"}},
+		{&data.Author, []string{"log", "-1", "--format=%an", commit}},
+	} {
+		output, err := runGit(field.args...)
+		if err != nil {
+			return templateData{}, err
+		}
+		*field.dest = strings.TrimSpace(string(output))
+	}
+	return data, nil
+}
+
+// templateDelims are the action delimiters of a template; empty values
+// select the text/template defaults "{{" and "}}"
+type templateDelims struct {
+	left, right string
+}
+
+// parseTemplateDelims parses a --template-delims value such as "<< >>"
+func parseTemplateDelims(value string) (templateDelims, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return templateDelims{}, fmt.Errorf("expected two space-separated delimiters, got %q", value)
+	}
+	return templateDelims{left: fields[0], right: fields[1]}, nil
+}
+
+// parseTemplateFile parses the message template at path
+func parseTemplateFile(path string, delims templateDelims) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Delims(delims.left, delims.right).ParseFiles(path)
+}
+
+// referencesField reports whether any template defined in tmpl refers to the
+// templateData field name, as .Name or $.Name
+func referencesField(tmpl *template.Template, name string) bool {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && nodeReferencesField(t.Tree.Root, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeReferencesField(node parse.Node, name string) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if nodeReferencesField(child, name) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return nodeReferencesField(n.Pipe, name)
+	case *parse.IfNode:
+		return nodeReferencesField(&n.BranchNode, name)
+	case *parse.RangeNode:
+		return nodeReferencesField(&n.BranchNode, name)
+	case *parse.WithNode:
+		return nodeReferencesField(&n.BranchNode, name)
+	case *parse.BranchNode:
+		return nodeReferencesField(n.Pipe, name) || nodeReferencesField(n.List, name) || nodeReferencesField(n.ElseList, name)
+	case *parse.TemplateNode:
+		return nodeReferencesField(n.Pipe, name)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if nodeReferencesField(cmd, name) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if nodeReferencesField(arg, name) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return nodeReferencesField(n.Node, name)
+	case *parse.FieldNode:
+		return n.Ident[0] == name
+	case *parse.VariableNode:
+		return len(n.Ident) > 1 && n.Ident[0] == "$" && n.Ident[1] == name
+	}
+	return false
+}
+
+// renderTemplate executes tmpl with data, dropping trailing newlines
+func renderTemplate(tmpl *template.Template, data templateData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// appendParagraph appends text to message separated by a blank line.
+// Empty text leaves message unchanged.
+func appendParagraph(message, text string) string {
+	if strings.TrimSpace(text) == "" {
+		return message
+	}
+	return message + "\n\n" + text
+}
+
+// appendParagraphs appends each of paragraphs to message with appendParagraph
+func appendParagraphs(message string, paragraphs []string) string {
+	for _, text := range paragraphs {
+		message = appendParagraph(message, text)
+	}
+	return message
+}
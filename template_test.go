@@ -0,0 +1,247 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	fakeGit(t, func(stdin string, args []string) (string, error) {
+		switch strings.Join(args, " ") {
+		case "rev-parse --short HEAD":
+			return "abc1234\n", nil
+		case "rev-parse --abbrev-ref HEAD":
+			return "main\n", nil
+		case "log -1 --format=%an HEAD":
+			return "Jane Doe\n", nil
+		}
+		t.Fatalf("unexpected git call: %q", args)
+		return "", nil
+	})
+
+	got, err := newTemplateData("v1.2.0", "HEAD", "## [v1.2.0]")
+	if err != nil {
+		t.Fatalf("newTemplateData() error = %v", err)
+	}
+
+	want := templateData{
+		Tag:       "v1.2.0",
+		Version:   "1.2.0",
+		Changelog: "## [v1.2.0]",
+		Commit:    "abc1234",
+		Branch:    "main",
+		Author:    "Jane Doe",
+	}
+	if got != want {
+		t.Errorf("newTemplateData() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := templateData{
+		Tag:       "v1.2.0",
+		Version:   "1.2.0",
+		Changelog: "- New feature",
+		Commit:    "abc1234",
+		Branch:    "main",
+		Author:    "Jane Doe",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "all placeholders",
+			template: `Release {{.Version}} ({{.Tag}})
+
+{{.Changelog}}
+
+Built from {{.Commit}} on {{.Branch}} by {{.Author}}
+`,
+			want: `Release 1.2.0 (v1.2.0)
+
+- New feature
+
+Built from abc1234 on main by Jane Doe`,
+		},
+		{
+			name:     "unknown field",
+			template: "{{.Missing}}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "message.tmpl")
+			if err := os.WriteFile(path, []byte(tt.template), 0644); err != nil {
+				t.Fatalf("Failed to create test template: %v", err)
+			}
+
+			tmpl, err := parseTemplateFile(path, templateDelims{})
+			if err != nil {
+				t.Fatalf("parseTemplateFile() error = %v", err)
+			}
+			got, err := renderTemplate(tmpl, data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("renderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("renderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Changelog"), 0644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+	if _, err := parseTemplateFile(path, templateDelims{}); err == nil {
+		t.Error("parseTemplateFile() with unterminated action expected error, got nil")
+	}
+}
+
+func TestFooterFollowsBody(t *testing.T) {
+	data := templateData{Tag: "v1.2.0", Version: "1.2.0", Changelog: "## [v1.2.0]\n\n- New feature"}
+
+	dir := t.TempDir()
+	bodyPath := filepath.Join(dir, "body.tmpl")
+	footerPath := filepath.Join(dir, "footer.tmpl")
+	if err := os.WriteFile(bodyPath, []byte("{{.Changelog}}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+	if err := os.WriteFile(footerPath, []byte("Downloads: https://example.com/{{.Tag}}\nLicensed under MIT\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+
+	var rendered []string
+	for _, path := range []string{bodyPath, footerPath} {
+		tmpl, err := parseTemplateFile(path, templateDelims{})
+		if err != nil {
+			t.Fatalf("parseTemplateFile() error = %v", err)
+		}
+		text, err := renderTemplate(tmpl, data)
+		if err != nil {
+			t.Fatalf("renderTemplate() error = %v", err)
+		}
+		rendered = append(rendered, text)
+	}
+
+	got := appendParagraph(rendered[0], rendered[1])
+	want := `## [v1.2.0]
+
+- New feature
+
+Downloads: https://example.com/v1.2.0
+Licensed under MIT`
+	if got != want {
+		t.Errorf("appendParagraph() = %q, want %q", got, want)
+	}
+}
+
+func TestAppendParagraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		text    string
+		want    string
+	}{
+		{"appends with blank line", "body", "footer", "body\n\nfooter"},
+		{"empty text is ignored", "body", "", "body"},
+		{"whitespace text is ignored", "body", " \n", "body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendParagraph(tt.message, tt.text); got != tt.want {
+				t.Errorf("appendParagraph() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateDelims(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    templateDelims
+		wantErr bool
+	}{
+		{"<< >>", templateDelims{left: "<<", right: ">>"}, false},
+		{"  [[   ]]  ", templateDelims{left: "[[", right: "]]"}, false},
+		{"<<", templateDelims{}, true},
+		{"<< >> !!", templateDelims{}, true},
+		{"", templateDelims{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTemplateDelims(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTemplateDelims(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTemplateDelims(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCustomDelimsKeepBraces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message.tmpl")
+	content := "Release << .Version >>\n\nUsage: {{ .Values.image }}\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+
+	tmpl, err := parseTemplateFile(path, templateDelims{left: "<<", right: ">>"})
+	if err != nil {
+		t.Fatalf("parseTemplateFile() error = %v", err)
+	}
+	got, err := renderTemplate(tmpl, templateData{Version: "1.2.0"})
+	if err != nil {
+		t.Fatalf("renderTemplate() error = %v", err)
+	}
+	if want := "Release 1.2.0\n\nUsage: {{ .Values.image }}"; got != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+}
+
+func TestReferencesField(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"plain placeholder", "Release {{.Tag}}\n\n{{.Changelog}}\n", true},
+		{"inside if", "{{if .Tag}}{{.Changelog}}{{end}}", true},
+		{"in else branch", "{{if .Commit}}none{{else}}{{.Changelog}}{{end}}", true},
+		{"function argument", "{{printf \"%s\" .Changelog}}", true},
+		{"root variable in range", "{{range .Tag}}{{$.Changelog}}{{end}}", true},
+		{"named template", "{{define \"body\"}}{{.Changelog}}{{end}}{{template \"body\" .}}", true},
+		{"other fields only", "Release {{.Tag}} at {{.Commit}}\n", false},
+		{"literal text", "Changelog: see CHANGELOG.md\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "annotate.tmpl")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test template: %v", err)
+			}
+			tmpl, err := parseTemplateFile(path, templateDelims{})
+			if err != nil {
+				t.Fatalf("parseTemplateFile() error = %v", err)
+			}
+			if got := referencesField(tmpl, "Changelog"); got != tt.want {
+				t.Errorf("referencesField() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
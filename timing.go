@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// phaseTiming is how long one named phase of a run took
+type phaseTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// phaseTimer measures the phases of a run for --timing. A nil *phaseTimer
+// is valid and records nothing, so callers need not check whether timing
+// is enabled.
+type phaseTimer struct {
+	now    func() time.Time
+	start  time.Time
+	last   time.Time
+	phases []phaseTiming
+}
+
+func newPhaseTimer(now func() time.Time) *phaseTimer {
+	start := now()
+	return &phaseTimer{now: now, start: start, last: start}
+}
+
+// done records the time since the previous phase ended as phase name. A
+// phase that is done again, such as the checks resumed after a fetch, adds
+// to its earlier time.
+func (t *phaseTimer) done(name string) {
+	if t == nil {
+		return
+	}
+	now := t.now()
+	elapsed := now.Sub(t.last)
+	t.last = now
+	for i := range t.phases {
+		if t.phases[i].Name == name {
+			t.phases[i].Duration += elapsed
+			return
+		}
+	}
+	t.phases = append(t.phases, phaseTiming{Name: name, Duration: elapsed})
+}
+
+// skip excludes the time since the previous phase ended, such as waiting
+// for a prompt, from the next phase
+func (t *phaseTimer) skip() {
+	if t == nil {
+		return
+	}
+	t.last = t.now()
+}
+
+// total returns the time since the timer started
+func (t *phaseTimer) total() time.Duration {
+	return t.now().Sub(t.start)
+}
+
+// summary formats the recorded phases and the total, one per line
+func (t *phaseTimer) summary() []string {
+	width := len("total")
+	for _, phase := range t.phases {
+		width = max(width, len(phase.Name))
+	}
+	var lines []string
+	for _, phase := range t.phases {
+		lines = append(lines, fmt.Sprintf("  %-*s  %s", width, phase.Name, formatDuration(phase.Duration)))
+	}
+	lines = append(lines, fmt.Sprintf("  %-*s  %s", width, "total", formatDuration(t.total())))
+	return lines
+}
+
+// milliseconds returns the recorded phases and the total in milliseconds,
+// or nil if t is nil. Phase names are snake_case, like every JSON key.
+func (t *phaseTimer) milliseconds() map[string]float64 {
+	if t == nil {
+		return nil
+	}
+	ms := make(map[string]float64, len(t.phases)+1)
+	for _, phase := range t.phases {
+		ms[strings.ReplaceAll(phase.Name, "-", "_")] = float64(phase.Duration.Microseconds()) / 1000
+	}
+	ms["total"] = float64(t.total().Microseconds()) / 1000
+	return ms
+}
+
+// printTimings prints the summary of t through the progress output, if set
+func printTimings(t *phaseTimer) {
+	if t == nil {
+		return
+	}
+	printSuccess("Timing:\n" + strings.Join(t.summary(), "\n"))
+}
+
+// formatDuration rounds d to milliseconds, or microseconds below 1ms
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
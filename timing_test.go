@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPhaseTimer(t *testing.T) {
+	clock := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	now := func() time.Time { return clock }
+	timer := newPhaseTimer(now)
+
+	clock = clock.Add(20 * time.Millisecond)
+	timer.done("checks")
+	clock = clock.Add(1500 * time.Millisecond)
+	timer.done("message")
+	// Time spent at a prompt is left out of the next phase
+	clock = clock.Add(10 * time.Second)
+	timer.skip()
+	clock = clock.Add(250 * time.Microsecond)
+	timer.done("tag")
+	clock = clock.Add(time.Millisecond)
+	timer.done("post-tag")
+	// A phase done again adds to its earlier time
+	clock = clock.Add(5 * time.Millisecond)
+	timer.done("checks")
+
+	want := []string{
+		"  checks    25ms",
+		"  message   1.5s",
+		"  tag       250µs",
+		"  post-tag  1ms",
+		"  total     11.526s",
+	}
+	if got := timer.summary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+
+	wantMS := map[string]float64{"checks": 25, "message": 1500, "tag": 0.25, "post_tag": 1, "total": 11526.25}
+	if got := timer.milliseconds(); !reflect.DeepEqual(got, wantMS) {
+		t.Errorf("milliseconds() = %v, want %v", got, wantMS)
+	}
+}
+
+func TestPhaseTimerNil(t *testing.T) {
+	var timer *phaseTimer
+	timer.done("checks")
+	timer.skip()
+	printTimings(timer)
+	if got := timer.milliseconds(); got != nil {
+		t.Errorf("milliseconds() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Minimum git version providing "git interpret-trailers --parse"
+const (
+	trailersMinGitMajor = 2
+	trailersMinGitMinor = 15
+)
+
+// normalizeTrailers passes message through git interpret-trailers so that
+// its trailers (References, Released-by, ...) are consistently formatted and
+// exact duplicates are dropped. A message without trailers is returned as is.
+func normalizeTrailers(message string) (string, error) {
+	output, err := gitExec(message+"\n", nil, "interpret-trailers", "--parse")
+	if err != nil {
+		return "", err
+	}
+
+	var trailers []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			trailers = append(trailers, line)
+		}
+	}
+	if len(trailers) == 0 {
+		return message, nil
+	}
+
+	// git only recognizes trailers in the last paragraph, so everything
+	// before it is the body
+	body := ""
+	if i := strings.LastIndex(strings.TrimRight(message, "\n"), "\n\n"); i >= 0 {
+		body = message[:i]
+	}
+
+	// Re-add the parsed trailers one by one, letting git skip the ones
+	// that are already present with the same value
+	args := []string{"interpret-trailers", "--where", "end", "--if-exists", "addIfDifferent"}
+	for _, trailer := range trailers {
+		args = append(args, "--trailer", trailer)
+	}
+	output, err = gitExec(body+"\n", nil, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(string(output), "\n"), nil
+}
+
+// trailerLineRegex matches a "Token: value" trailer line
+var trailerLineRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*:\s`)
+
+// appendTrailer adds trailer after the existing trailers of message, starting
+// a trailer block if the last paragraph isn't one. A trailer that is
+// already present is not added again.
+func appendTrailer(message, trailer string) string {
+	message = strings.TrimRight(message, "\n")
+	lastParagraph := message
+	if i := strings.LastIndex(message, "\n\n"); i >= 0 {
+		lastParagraph = message[i+2:]
+	}
+
+	isTrailerBlock := lastParagraph != "" && message != lastParagraph
+	for _, line := range strings.Split(lastParagraph, "\n") {
+		if line == trailer {
+			return message
+		}
+		if !trailerLineRegex.MatchString(line) {
+			isTrailerBlock = false
+		}
+	}
+	if isTrailerBlock {
+		return message + "\n" + trailer
+	}
+	return appendParagraph(message, trailer)
+}
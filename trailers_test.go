@@ -0,0 +1,117 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTrailers(t *testing.T) {
+	tests := []struct {
+		name         string
+		message      string
+		parsed       string
+		wantBody     string
+		wantTrailers []string
+		want         string
+	}{
+		{
+			name: "duplicate trailers are re-added individually",
+			message: `## [v1.0.0] - 2025-08-26
+
+- Initial release
+
+References: #1
+released-by:  bob
+References: #1`,
+			parsed:       "References: #1\nreleased-by: bob\nReferences: #1\n",
+			wantBody:     "## [v1.0.0] - 2025-08-26\n\n- Initial release\n",
+			wantTrailers: []string{"References: #1", "released-by: bob", "References: #1"},
+			want: `## [v1.0.0] - 2025-08-26
+
+- Initial release
+
+References: #1
+released-by: bob`,
+		},
+		{
+			name:    "message without trailers is unchanged",
+			message: "## [v1.0.0] - 2025-08-26\n\n- Initial release",
+			parsed:  "",
+			want:    "## [v1.0.0] - 2025-08-26\n\n- Initial release",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody string
+			var gotTrailers []string
+			fakeGit(t, func(stdin string, args []string) (string, error) {
+				if reflect.DeepEqual(args, []string{"interpret-trailers", "--parse"}) {
+					return tt.parsed, nil
+				}
+
+				gotBody = stdin
+				for i, arg := range args {
+					if arg == "--trailer" {
+						gotTrailers = append(gotTrailers, args[i+1])
+					}
+				}
+				// Simulate git dropping the duplicate trailer
+				return strings.TrimSuffix(stdin, "\n") + "\n\nReferences: #1\nreleased-by: bob\n", nil
+			})
+
+			got, err := normalizeTrailers(tt.message)
+			if err != nil {
+				t.Fatalf("normalizeTrailers() error = %v", err)
+			}
+			if gotBody != tt.wantBody {
+				t.Errorf("normalizeTrailers() body passed to git = %q, want %q", gotBody, tt.wantBody)
+			}
+			if !reflect.DeepEqual(gotTrailers, tt.wantTrailers) {
+				t.Errorf("normalizeTrailers() trailers = %q, want %q", gotTrailers, tt.wantTrailers)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeTrailers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendTrailer(t *testing.T) {
+	const signoff = "Signed-off-by: Jane Doe <jane@example.com>"
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "starts a trailer block",
+			message: "### Added\n- Feature",
+			want:    "### Added\n- Feature\n\n" + signoff,
+		},
+		{
+			name:    "goes after existing trailers",
+			message: "### Added\n- Feature\n\nReferences: #12\nReleased-by: CI",
+			want:    "### Added\n- Feature\n\nReferences: #12\nReleased-by: CI\n" + signoff,
+		},
+		{
+			name:    "not duplicated",
+			message: "### Added\n- Feature\n\n" + signoff + "\n",
+			want:    "### Added\n- Feature\n\n" + signoff,
+		},
+		{
+			name:    "single paragraph is not a trailer block",
+			message: "Note: tagged from CI",
+			want:    "Note: tagged from CI\n\n" + signoff,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendTrailer(tt.message, signoff); got != tt.want {
+				t.Errorf("appendTrailer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// exitVerifyFailed is the exit status when the --verify-command release gate
+// fails, distinct from the general failure status 1
+const exitVerifyFailed = 3
+
+// shellCommand returns the argv that runs command through the shell of goos
+func shellCommand(goos, command string) []string {
+	if goos == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+	return []string{"sh", "-c", command}
+}
+
+// runVerifyCommand runs the release gate command for tagName at commit,
+// streaming its output to w and os.Stderr. GTAUTO_TAG and GTAUTO_COMMIT are
+// set in its environment. A non-zero exit status is returned as an error.
+func runVerifyCommand(command, tagName, commit string, w io.Writer) error {
+	args := shellCommand(runtime.GOOS, command)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "GTAUTO_TAG="+tagName, "GTAUTO_COMMIT="+commit)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
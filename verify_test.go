@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestShellCommand(t *testing.T) {
+	tests := []struct {
+		goos string
+		want []string
+	}{
+		{"linux", []string{"sh", "-c", "make test"}},
+		{"darwin", []string{"sh", "-c", "make test"}},
+		{"windows", []string{"cmd", "/C", "make test"}},
+	}
+
+	for _, tt := range tests {
+		if got := shellCommand(tt.goos, "make test"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shellCommand(%q) = %v, want %v", tt.goos, got, tt.want)
+		}
+	}
+}
+
+func TestRunVerifyCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+
+	var buf bytes.Buffer
+	if err := runVerifyCommand(`echo "$GTAUTO_TAG $GTAUTO_COMMIT"`, "v1.0.0", "abc123", &buf); err != nil {
+		t.Fatalf("runVerifyCommand() error = %v", err)
+	}
+	if got := buf.String(); got != "v1.0.0 abc123\n" {
+		t.Errorf("runVerifyCommand() output = %q, want %q", got, "v1.0.0 abc123\n")
+	}
+
+	if err := runVerifyCommand("exit 2", "v1.0.0", "abc123", &buf); err == nil {
+		t.Error("runVerifyCommand() with a failing command returned nil")
+	}
+}